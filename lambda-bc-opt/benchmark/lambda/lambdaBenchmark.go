@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"flag"
 	"log/slog"
+	"math/bits"
 	"os"
-	"sort"
 	"sync"
 	"time"
 	"encoding/json"
@@ -36,7 +36,116 @@ func createLambdaClient() *lambda.Client {
 	return lambda.NewFromConfig(cfg)
 }
 
-func invokeLambda(client *lambda.Client, functionName string, numCalls int, wg *sync.WaitGroup, durations *[]time.Duration, mu *sync.Mutex) {
+// latencyRecorder below is intentionally the same log-linear histogram as
+// openwhisk/redis/batching-agent/pkg/stats.Recorder rather than an import of
+// it: this file lives under lambda-bc-opt, which has no go.mod of its own
+// (it's an unmodularized source snapshot, not a Go module), so it has no way
+// to depend on a package belonging to the redis-batching-agent module. If
+// lambda-bc-opt is ever turned into a proper module, this type should be
+// deleted in favor of importing pkg/stats directly.
+//
+// subBucketsPerMagnitude and magnitudeCount size latencyRecorder's log-linear
+// histogram: each power-of-two magnitude band of microseconds [2^m, 2^(m+1))
+// is divided into subBucketsPerMagnitude equal-width linear buckets, so
+// numInvocations invocations can be aggregated in a fixed, small amount of
+// memory instead of a []time.Duration that has to be sorted afterwards.
+const (
+	subBucketsPerMagnitude = 32
+	magnitudeCount         = 32
+	bucketCount            = magnitudeCount * subBucketsPerMagnitude
+)
+
+// latencyRecorder is a concurrency-safe execution-time histogram, recorded
+// into directly from each invokeLambda goroutine instead of appending to a
+// shared slice under a mutex and sorting it once every invocation is done.
+type latencyRecorder struct {
+	mu      sync.Mutex
+	buckets [bucketCount]int64
+	count   int64
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{}
+}
+
+func (r *latencyRecorder) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	idx := latencyBucketIndex(d)
+
+	r.mu.Lock()
+	r.buckets[idx]++
+	r.count++
+	r.mu.Unlock()
+}
+
+func latencyBucketIndex(d time.Duration) int {
+	us := uint64(d.Microseconds()) + 1 // +1 so a 0us sample lands in magnitude 0, not -Inf
+	magnitude := bits.Len64(us) - 1
+	if magnitude >= magnitudeCount {
+		magnitude = magnitudeCount - 1
+	}
+
+	bandStart := uint64(1) << uint(magnitude)
+	bandWidth := bandStart // band is [bandStart, 2*bandStart)
+	offset := (us - bandStart) * subBucketsPerMagnitude / bandWidth
+	if offset >= subBucketsPerMagnitude {
+		offset = subBucketsPerMagnitude - 1
+	}
+	return magnitude*subBucketsPerMagnitude + int(offset)
+}
+
+func latencyBucketUpperBound(idx int) time.Duration {
+	magnitude := idx / subBucketsPerMagnitude
+	offset := idx % subBucketsPerMagnitude
+
+	bandStart := uint64(1) << uint(magnitude)
+	bandWidth := bandStart
+	us := bandStart + (bandWidth*uint64(offset+1))/subBucketsPerMagnitude
+	return time.Duration(us) * time.Microsecond
+}
+
+// percentiles is a point-in-time read of p50/p90/p99/p999 off the recorder.
+type percentiles struct {
+	count      int64
+	p50, p90, p99, p999 time.Duration
+}
+
+func (r *latencyRecorder) snapshot() percentiles {
+	r.mu.Lock()
+	buckets := r.buckets
+	count := r.count
+	r.mu.Unlock()
+
+	if count == 0 {
+		return percentiles{}
+	}
+
+	return percentiles{
+		count: count,
+		p50:   latencyPercentile(buckets[:], count, 50),
+		p90:   latencyPercentile(buckets[:], count, 90),
+		p99:   latencyPercentile(buckets[:], count, 99),
+		p999:  latencyPercentile(buckets[:], count, 99.9),
+	}
+}
+
+// latencyPercentile returns the smallest bucket's upper bound such that at
+// least pct percent of count samples fall at or below it.
+func latencyPercentile(buckets []int64, count int64, pct float64) time.Duration {
+	target := int64(float64(count) * pct / 100)
+	var cumulative int64
+	for i, c := range buckets {
+		cumulative += c
+		if cumulative > target {
+			return latencyBucketUpperBound(i)
+		}
+	}
+	return latencyBucketUpperBound(len(buckets) - 1)
+}
+
+func invokeLambda(client *lambda.Client, functionName string, numCalls int, wg *sync.WaitGroup, latency *latencyRecorder) {
 	defer wg.Done()
 
 	slog.Info("Invocation started!")
@@ -70,28 +179,14 @@ func invokeLambda(client *lambda.Client, functionName string, numCalls int, wg *
 	slog.Info(fmt.Sprintf("output is => %s", responsePayload))
 
 	executionTime := time.Since(startTime)
-
-	// Safely append the execution time to the durations slice
-	mu.Lock()
-	*durations = append(*durations, executionTime)
-	mu.Unlock()
+	latency.record(executionTime)
 
 	slog.Info(fmt.Sprintf("Successfully invoked Lambda function: %s, Execution Time: %v",
 		functionName,
 		executionTime))
 }
 
-func calculatePercentile(durations []time.Duration, percentile float64) time.Duration {
-	if len(durations) == 0 {
-		return 0
-	}
-
-	index := int(float64(len(durations)-1) * percentile / 100)
-	return durations[index]
-}
-
-
-func writePercentilesToFile(outputName string, p50, p90, p99 time.Duration) {
+func writePercentilesToFile(outputName string, p percentiles) {
 	file, err := os.Create(outputName)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to create output file %s: %v", outputName, err))
@@ -105,19 +200,25 @@ func writePercentilesToFile(outputName string, p50, p90, p99 time.Duration) {
 		return
 	}
 
-	_, err = file.WriteString(fmt.Sprintf("50th,%v\n", p50))
+	_, err = file.WriteString(fmt.Sprintf("50th,%v\n", p.p50))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to write to output file %s: %v", outputName, err))
 		return
 	}
 
-	_, err = file.WriteString(fmt.Sprintf("90th,%v\n", p90))
+	_, err = file.WriteString(fmt.Sprintf("90th,%v\n", p.p90))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to write to output file %s: %v", outputName, err))
 		return
 	}
 
-	_, err = file.WriteString(fmt.Sprintf("99th,%v\n", p99))
+	_, err = file.WriteString(fmt.Sprintf("99th,%v\n", p.p99))
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to write to output file %s: %v", outputName, err))
+		return
+	}
+
+	_, err = file.WriteString(fmt.Sprintf("99.9th,%v\n", p.p999))
 	if err != nil {
 		slog.Error(fmt.Sprintf("Failed to write to output file %s: %v", outputName, err))
 		return
@@ -163,34 +264,26 @@ func main() {
 
 	var wg sync.WaitGroup
 
-	var durations []time.Duration
-	var mu sync.Mutex
+	latency := newLatencyRecorder()
 
 	for i := 0; i < numInvocations; i++ {
 		<-ticker.C // Wait for the next tick to respect the rate limit
 
 		wg.Add(1)
-		go invokeLambda(lambdaClient, functionName, numCalls, &wg, &durations, &mu)
+		go invokeLambda(lambdaClient, functionName, numCalls, &wg, latency)
 	}
 
 	wg.Wait()
 
+	// Calculate and log the 50th, 90th, 99th, and 99.9th percentiles
+	p := latency.snapshot()
 
-	sort.Slice(durations, func(i, j int) bool {
-		return durations[i] < durations[j]
-	})
-
-	// Calculate and log the 50th, 90th, and 99th percentiles
-	p50 := calculatePercentile(durations, 50)
-	p90 := calculatePercentile(durations, 90)
-	p99 := calculatePercentile(durations, 99)
-
-
-	slog.Info(fmt.Sprintf("50th Percentile Execution Time: %v", p50))
-	slog.Info(fmt.Sprintf("90th Percentile Execution Time: %v", p90))
-	slog.Info(fmt.Sprintf("99th Percentile Execution Time: %v", p99))
+	slog.Info(fmt.Sprintf("50th Percentile Execution Time: %v", p.p50))
+	slog.Info(fmt.Sprintf("90th Percentile Execution Time: %v", p.p90))
+	slog.Info(fmt.Sprintf("99th Percentile Execution Time: %v", p.p99))
+	slog.Info(fmt.Sprintf("99.9th Percentile Execution Time: %v", p.p999))
 
-	writePercentilesToFile(outputName, p50, p90, p99)
+	writePercentilesToFile(outputName, p)
 
 	slog.Info("All Lambda invocations completed.")
 }
@@ -1,23 +1,41 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"cloud.google.com/go/storage"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/gorilla/mux"
 	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/batching"
+	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/prefetch"
+	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/sinks"
+	_ "github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/sinks/sqs"
+	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Configuration holds the agent's configuration
@@ -26,79 +44,384 @@ type Configuration struct {
 	BatchingEnabled   bool
 	BatchWindow       time.Duration
 	MaxBatchSize      int
+	MaxBufferBytes    int64
 	DebugMode         bool
 	AwsRegion         string
 	DefaultBucketName string
+
+	// AdaptiveBatching, when true, has every queue (S3, SQS, Kinesis) derive
+	// its batch window from an EWMA of observed inter-arrival times instead
+	// of using its configured *BatchWindow as a fixed value, clamped to
+	// [MinBatchWindow, MaxBatchWindow]. Off by default so benchmark runs
+	// stay reproducible; the fixed windows below remain each queue's
+	// starting point either way.
+	AdaptiveBatching bool
+	MinBatchWindow   time.Duration
+	MaxBatchWindow   time.Duration
+
+	// Backend selects which object store GetObject/ListObjects/ListBuckets
+	// run against: "s3" (default), "gcs", "azure", or "minio" (any
+	// S3-compatible endpoint, e.g. MinIO or Cloudflare R2). The batching and
+	// HTTP handler code is identical across backends; only how the
+	// ObjectStore is constructed in NewBatchingAgent differs.
+	Backend string
+
+	// MinIOEndpoint/MinIOUsePathStyle configure the "minio" backend: the
+	// base endpoint to hit instead of AWS's regional S3 endpoints, and
+	// whether to address buckets path-style (bucket in the path, not the
+	// host) since most S3-compatible servers don't support virtual-hosted
+	// buckets.
+	MinIOEndpoint     string
+	MinIOUsePathStyle bool
+
+	// AzureStorageAccount is the storage account name for the "azure"
+	// backend; buckets map to containers within this account.
+	AzureStorageAccount string
+
+	// SQSQueueURL is the default queue SendMessage requests go to when the
+	// caller doesn't specify one; SQSBatchWindow/SQSMaxBatchSize govern the
+	// SQS fan-in queue independently of the S3 settings above.
+	SQSQueueURL     string
+	SQSBatchWindow  time.Duration
+	SQSMaxBatchSize int
+
+	// KinesisStreamName is the default stream PutRecord requests go to when
+	// the caller doesn't specify one; KinesisBatchWindow/KinesisMaxBatchSize
+	// govern the Kinesis fan-in queue independently of the S3 settings above.
+	KinesisStreamName   string
+	KinesisBatchWindow  time.Duration
+	KinesisMaxBatchSize int
+
+	// EventSink selects where the batcher emits one structured event per
+	// completed S3 request (see pkg/sinks): "" (default) disables emission,
+	// "sqs" forwards events to EventSinkSQSQueueURL via SendMessageBatch.
+	// Distinct from SQSQueueURL above, which is the destination for
+	// passthrough SendMessage requests, not batcher telemetry.
+	EventSink            string
+	EventSinkSQSQueueURL string
+
+	// MaxRetries/RetryBaseDelay configure submitWithResilience's retry of
+	// transient (throttling/5xx) failures; BreakerThreshold/BreakerCooldown
+	// configure the per-BatchRequest.Type circuit breaker that short-circuits
+	// submissions with a 503 once a type has failed too many times in a row.
+	MaxRetries       int
+	RetryBaseDelay   time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// PrefetchConfig, if set, enables the pkg/prefetch subsystem: the path to
+	// a JSON config file listing {name, bucket, key} entries to keep pulled
+	// into PrefetchDir. PrefetchWorkers bounds how many pulls run at once;
+	// retries reuse MaxRetries/RetryBaseDelay above.
+	PrefetchConfig  string
+	PrefetchDir     string
+	PrefetchWorkers int
+
+	// StreamThresholdBytes, if positive, has handleGetObject HEAD the object
+	// first and, for anything larger, bypass the batcher entirely and stream
+	// the body straight from the object store to the client — large reads
+	// rarely benefit from coalescing and shouldn't wait on a batch window or
+	// be buffered in memory. 0 (the default) disables the check: every
+	// GetObject goes through the batcher.
+	StreamThresholdBytes int64
+
+	// OTLPEndpoint, if set, points pkg/tracing at an OTLP/gRPC collector and
+	// enables spans for every HTTP request, batch dispatch, and underlying
+	// AWS SDK call. "" (the default) disables tracing entirely.
+	OTLPEndpoint string
+
+	// MetricsAddr, if set, serves /metrics on its own listener instead of
+	// the main API router, so Prometheus scrapes don't share a port with
+	// client traffic. "" (the default) mounts /metrics on the main router,
+	// as before.
+	MetricsAddr string
 }
 
-// BatchingAgent handles S3 requests and optionally batches them
+// BatchingAgent handles S3, SQS, and Kinesis requests and optionally batches them
 type BatchingAgent struct {
-	config  Configuration
-	s3Client *s3.Client
-	batcher  *batching.S3Batcher
-	server   *http.Server
-	router   *mux.Router
-	mu       sync.Mutex
+	config        Configuration
+	store         batching.ObjectStore
+	sqsClient     *sqs.Client
+	kinesisClient *kinesis.Client
+	batcher       *batching.AWSBatcher
+	server        *http.Server
+	router        *mux.Router
+	mu            sync.Mutex
+
+	retryPolicy batching.RetryPolicy
+	breaker     *batching.CircuitBreaker
+
+	prefetch *prefetch.Manager
+
+	// tracingShutdown flushes pending spans; set by tracing.Setup in
+	// NewBatchingAgent and called from Shutdown. A no-op when OTLPEndpoint
+	// is unset.
+	tracingShutdown func(context.Context) error
+
+	// metricsServer serves /metrics on its own listener when config.
+	// MetricsAddr is set; nil (the default) mounts /metrics on router
+	// instead.
+	metricsServer *http.Server
 }
 
 // NewBatchingAgent creates a new batching agent
 func NewBatchingAgent(config Configuration) (*BatchingAgent, error) {
+	tracingShutdown, err := tracing.Setup(context.Background(), config.OTLPEndpoint, "batching-agent")
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
 	// Configure AWS SDK
-	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), 
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(),
 		awsconfig.WithRegion(config.AwsRegion),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
 	}
+	otelaws.AppendMiddlewares(&cfg.APIOptions)
 
-	s3Client := s3.NewFromConfig(cfg)
+	store, err := newObjectStore(context.Background(), cfg, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct %s object store: %w", config.Backend, err)
+	}
+	sqsClient := sqs.NewFromConfig(cfg)
+	kinesisClient := kinesis.NewFromConfig(cfg)
+
+	var sink sinks.Sink
+	if config.EventSink != "" {
+		sink, err = sinks.Build(config.EventSink, eventSinkConfig(config))
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct %s event sink: %w", config.EventSink, err)
+		}
+	}
 
 	// Create router and server
 	router := mux.NewRouter()
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.Port),
-		Handler: router,
+		Handler: otelhttp.NewHandler(router, "batching-agent"),
 	}
 
 	// Create agent
 	agent := &BatchingAgent{
-		config:  config,
-		s3Client: s3Client,
-		router:  router,
-		server:  server,
+		config:          config,
+		store:           store,
+		sqsClient:       sqsClient,
+		kinesisClient:   kinesisClient,
+		router:          router,
+		server:          server,
+		retryPolicy:     batching.RetryPolicy{MaxRetries: config.MaxRetries, BaseDelay: config.RetryBaseDelay},
+		breaker:         batching.NewCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		tracingShutdown: tracingShutdown,
 	}
 
 	// Initialize batcher
-	agent.batcher = batching.NewS3Batcher(
-		s3Client,
+	agent.batcher = batching.NewAWSBatcher(
+		store,
+		sqsClient,
+		kinesisClient,
 		config.BatchingEnabled,
-		config.BatchWindow,
-		config.MaxBatchSize,
+		batching.QueueConfig{
+			BatchWindow: config.BatchWindow, MaxBatchSize: config.MaxBatchSize,
+			Adaptive: config.AdaptiveBatching, MinBatchWindow: config.MinBatchWindow, MaxBatchWindow: config.MaxBatchWindow,
+		},
+		batching.QueueConfig{
+			BatchWindow: config.SQSBatchWindow, MaxBatchSize: config.SQSMaxBatchSize,
+			Adaptive: config.AdaptiveBatching, MinBatchWindow: config.MinBatchWindow, MaxBatchWindow: config.MaxBatchWindow,
+		},
+		batching.QueueConfig{
+			BatchWindow: config.KinesisBatchWindow, MaxBatchSize: config.KinesisMaxBatchSize,
+			Adaptive: config.AdaptiveBatching, MinBatchWindow: config.MinBatchWindow, MaxBatchWindow: config.MaxBatchWindow,
+		},
+		config.MaxBufferBytes,
+		sink,
 	)
 
+	if config.PrefetchConfig != "" {
+		agent.prefetch = prefetch.NewManager(config.PrefetchConfig, config.PrefetchDir, agent.batcher, config.PrefetchWorkers, agent.retryPolicy)
+	}
+
+	if config.MetricsAddr != "" {
+		metricsRouter := mux.NewRouter()
+		metricsRouter.Handle("/metrics", agent.batcher.Metrics().Handler()).Methods("GET")
+		agent.metricsServer = &http.Server{Addr: config.MetricsAddr, Handler: metricsRouter}
+	}
+
 	// Set up routes
 	agent.setupRoutes()
 
 	return agent, nil
 }
 
+// eventSinkConfig returns the configuration string for config.EventSink,
+// e.g. the queue URL for the "sqs" sink. Add a case here alongside a new
+// sink package's own flags as more destinations are supported.
+func eventSinkConfig(config Configuration) string {
+	switch config.EventSink {
+	case "sqs":
+		return config.EventSinkSQSQueueURL
+	default:
+		return ""
+	}
+}
+
+// newObjectStore constructs the ObjectStore for config.Backend. cfg is the
+// AWS config already loaded for S3/SQS/Kinesis; GCS and Azure use their own
+// SDK credential chains (Application Default Credentials and
+// DefaultAzureCredential respectively) rather than cfg.
+func newObjectStore(ctx context.Context, cfg awssdk.Config, config Configuration) (batching.ObjectStore, error) {
+	switch config.Backend {
+	case "", "s3":
+		return batching.NewS3Store(s3.NewFromConfig(cfg)), nil
+
+	case "minio":
+		if config.MinIOEndpoint == "" {
+			return nil, fmt.Errorf("minio backend requires --minio-endpoint")
+		}
+		return batching.NewMinIOStore(cfg, config.MinIOEndpoint, config.MinIOUsePathStyle), nil
+
+	case "gcs":
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCS client: %w", err)
+		}
+		return batching.NewGCSStore(client), nil
+
+	case "azure":
+		if config.AzureStorageAccount == "" {
+			return nil, fmt.Errorf("azure backend requires --azure-storage-account")
+		}
+		cred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+		}
+		serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", config.AzureStorageAccount)
+		client, err := azblob.NewClient(serviceURL, cred, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Azure Blob client: %w", err)
+		}
+		return batching.NewAzureStore(client), nil
+
+	default:
+		return nil, fmt.Errorf("unknown backend %q (want s3, gcs, azure, or minio)", config.Backend)
+	}
+}
+
 // setupRoutes configures the HTTP routes
 func (a *BatchingAgent) setupRoutes() {
 	// Health check
 	a.router.HandleFunc("/health", a.handleHealth).Methods("GET")
 
+	// Prometheus metrics, unless config.MetricsAddr moved it to its own
+	// listener (see NewBatchingAgent).
+	if a.metricsServer == nil {
+		a.router.Handle("/metrics", a.batcher.Metrics().Handler()).Methods("GET")
+	}
+
 	// S3 API endpoints
 	a.router.HandleFunc("/s3/listBuckets", a.handleListBuckets).Methods("GET")
 	a.router.HandleFunc("/s3/listObjects", a.handleListObjects).Methods("GET")
 	a.router.HandleFunc("/s3/getObject", a.handleGetObject).Methods("GET")
+	a.router.HandleFunc("/s3/putObject", a.handlePutObject).Methods("PUT")
+	a.router.HandleFunc("/s3/deleteObject", a.handleDeleteObject).Methods("DELETE")
+	a.router.HandleFunc("/s3/headObject", a.handleHeadObject).Methods("HEAD")
+
+	// SQS/Kinesis API endpoints
+	a.router.HandleFunc("/sqs/sendMessage", a.handleSendMessage).Methods("POST")
+	a.router.HandleFunc("/kinesis/putRecord", a.handlePutRecord).Methods("POST")
+
+	if a.prefetch != nil {
+		a.router.HandleFunc("/prefetch/status", a.handlePrefetchStatus).Methods("GET")
+	}
 
 	// Debug endpoints
 	if a.config.DebugMode {
 		a.router.HandleFunc("/debug/config", a.handleDebugConfig).Methods("GET")
+		a.router.HandleFunc("/debug/breakers", a.handleDebugBreakers).Methods("GET")
+	}
+}
+
+// requestCounter assigns each incoming HTTP request a process-unique id so
+// an operator can grep slog output to trace it through submission,
+// grouping, and the eventual backend call in pkg/batching.
+var requestCounter atomic.Int64
+
+func nextRequestID() string {
+	return fmt.Sprintf("req-%d", requestCounter.Add(1))
+}
+
+// errBreakerOpen is returned by submitWithResilience when request.Type's
+// circuit breaker is open; handlers translate it to a 503.
+var errBreakerOpen = errors.New("circuit breaker open for this request type")
+
+// writeSubmitError writes the appropriate HTTP response for an error
+// returned by submitWithResilience: 503 if it's errBreakerOpen, 500
+// otherwise.
+func writeSubmitError(w http.ResponseWriter, action string, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, errBreakerOpen) {
+		status = http.StatusServiceUnavailable
 	}
+	http.Error(w, fmt.Sprintf("Failed to %s: %v", action, err), status)
 }
 
-// Start starts the HTTP server
+// submitWithResilience submits request through a.batcher and waits for its
+// result, short-circuiting with errBreakerOpen if request.Type's circuit
+// breaker is currently open, and retrying a transient failure (see
+// batching.IsRetryable) up to a.config.MaxRetries times with exponential
+// backoff and jitter between attempts.
+//
+// Each attempt gets its own ResultChan/ErrorChan: a batcher that's still
+// processing a prior attempt (e.g. one that timed out from the caller's
+// perspective but eventually completes) must not deliver into a channel a
+// later attempt is also reading from. If request carries a body that a
+// retry would need to resend (e.g. a PutObject), rewind is called before
+// every attempt after the first to reset it; pass nil for requests with no
+// body to replay.
+func (a *BatchingAgent) submitWithResilience(ctx context.Context, request *batching.BatchRequest, rewind func()) (any, error) {
+	if !a.breaker.Allow(request.Type) {
+		return nil, errBreakerOpen
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= a.config.MaxRetries; attempt++ {
+		if attempt > 0 && rewind != nil {
+			rewind()
+		}
+
+		resultChan := make(chan any, 1)
+		errorChan := make(chan error, 1)
+		request.ResultChan = resultChan
+		request.ErrorChan = errorChan
+
+		a.batcher.Submit(ctx, request)
+
+		select {
+		case result := <-resultChan:
+			a.breaker.RecordResult(request.Type, nil)
+			return result, nil
+
+		case err := <-errorChan:
+			lastErr = err
+			a.breaker.RecordResult(request.Type, err)
+			if attempt == a.config.MaxRetries || !batching.IsRetryable(err) {
+				return nil, err
+			}
+
+			delay := a.retryPolicy.Backoff(attempt)
+			slog.Warn("batching: retrying request", "request_id", request.RequestID, "type", request.Type,
+				"attempt", attempt+1, "delay", delay, "error", err)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return nil, lastErr
+}
+
+// Start starts the HTTP server and, if configured, the prefetch subsystem.
 func (a *BatchingAgent) Start() {
 	go func() {
 		log.Printf("Starting batching agent on port %d", a.config.Port)
@@ -106,14 +429,40 @@ func (a *BatchingAgent) Start() {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
+
+	if a.metricsServer != nil {
+		go func() {
+			log.Printf("Serving metrics on %s", a.config.MetricsAddr)
+			if err := a.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start metrics server: %v", err)
+			}
+		}()
+	}
+
+	if a.prefetch != nil {
+		if err := a.prefetch.Start(context.Background()); err != nil {
+			log.Fatalf("Failed to start prefetch subsystem: %v", err)
+		}
+	}
 }
 
 // Shutdown gracefully shuts down the agent
 func (a *BatchingAgent) Shutdown(ctx context.Context) {
+	if a.prefetch != nil {
+		a.prefetch.Stop()
+	}
 	a.batcher.Shutdown()
 	if err := a.server.Shutdown(ctx); err != nil {
 		log.Printf("Error shutting down server: %v", err)
 	}
+	if a.metricsServer != nil {
+		if err := a.metricsServer.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down metrics server: %v", err)
+		}
+	}
+	if err := a.tracingShutdown(ctx); err != nil {
+		log.Printf("Error shutting down tracing: %v", err)
+	}
 }
 
 // Handler functions
@@ -124,41 +473,36 @@ func (a *BatchingAgent) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a *BatchingAgent) handleListBuckets(w http.ResponseWriter, r *http.Request) {
-	resultChan := make(chan any, 1)
-	errorChan := make(chan error, 1)
+	requestID := nextRequestID()
+	slog.Info("handling listBuckets", "request_id", requestID)
 
 	// Create a batch request
 	request := &batching.BatchRequest{
-		Type:       batching.TypeListBuckets,
-		ResultChan: resultChan,
-		ErrorChan:  errorChan,
+		Type:      batching.TypeListBuckets,
+		RequestID: requestID,
 	}
 
-	// Submit the request
-	a.batcher.Submit(request)
-
-	// Wait for the result
-	select {
-	case result := <-resultChan:
-		resp, ok := result.(*s3.ListBucketsOutput)
-		if !ok {
-			http.Error(w, "Invalid response type", http.StatusInternalServerError)
-			return
-		}
-
-		// Marshal the response
-		jsonData, err := json.Marshal(resp)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
-			return
-		}
+	result, err := a.submitWithResilience(r.Context(), request, nil)
+	if err != nil {
+		writeSubmitError(w, "list buckets", err)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(jsonData)
+	resp, ok := result.(*s3.ListBucketsOutput)
+	if !ok {
+		http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		return
+	}
 
-	case err := <-errorChan:
-		http.Error(w, fmt.Sprintf("Failed to list buckets: %v", err), http.StatusInternalServerError)
+	// Marshal the response
+	jsonData, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
 }
 
 func (a *BatchingAgent) handleListObjects(w http.ResponseWriter, r *http.Request) {
@@ -170,47 +514,270 @@ func (a *BatchingAgent) handleListObjects(w http.ResponseWriter, r *http.Request
 	prefix := r.URL.Query().Get("prefix")
 	maxKeys := int32(1000) // Default to 1000
 
-	resultChan := make(chan any, 1)
-	errorChan := make(chan error, 1)
+	requestID := nextRequestID()
+	slog.Info("handling listObjects", "request_id", requestID, "bucket", bucket, "prefix", prefix)
 
 	// Create a batch request
 	request := &batching.BatchRequest{
 		Type:       batching.TypeListObjects,
+		RequestID:  requestID,
 		BucketName: bucket,
 		Prefix:     prefix,
 		MaxKeys:    maxKeys,
-		ResultChan: resultChan,
-		ErrorChan:  errorChan,
 	}
 
-	// Submit the request
-	a.batcher.Submit(request)
+	result, err := a.submitWithResilience(r.Context(), request, nil)
+	if err != nil {
+		writeSubmitError(w, "list objects", err)
+		return
+	}
+
+	resp, ok := result.(*s3.ListObjectsV2Output)
+	if !ok {
+		http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		return
+	}
+
+	// Marshal the response
+	jsonData, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-	// Wait for the result
-	select {
-	case result := <-resultChan:
-		resp, ok := result.(*s3.ListObjectsV2Output)
-		if !ok {
-			http.Error(w, "Invalid response type", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// handleGetObject serves a single object, honoring the client's Range and
+// If-None-Match headers. Objects over config.StreamThresholdBytes bypass the
+// batcher entirely (see streamGetObject); everything else goes through the
+// normal coalesced path, which merges overlapping ranges across concurrent
+// requests for the same bucket:key (see executeGetObjectGroup).
+func (a *BatchingAgent) handleGetObject(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = a.config.DefaultBucketName
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing required parameter: key", http.StatusBadRequest)
+		return
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+
+	requestID := nextRequestID()
+	slog.Info("handling getObject", "request_id", requestID, "bucket", bucket, "key", key, "range", rangeHeader)
+
+	parsedRange, rangeOK := parseByteRange(rangeHeader)
+
+	// A HeadObject round trip is needed both to check the streaming
+	// threshold and to resolve an open-ended or suffix range against the
+	// object's total size, so make it once and reuse it for whichever of
+	// those apply.
+	var head *s3.HeadObjectOutput
+	if a.config.StreamThresholdBytes > 0 || (rangeOK && parsedRange.needsSize()) {
+		if h, err := a.store.HeadObject(r.Context(), &s3.HeadObjectInput{Bucket: &bucket, Key: &key}); err == nil {
+			head = h
+		}
+	}
+
+	if a.config.StreamThresholdBytes > 0 && head != nil && head.ContentLength != nil && *head.ContentLength > a.config.StreamThresholdBytes {
+		slog.Info("handling getObject: streaming directly, above threshold", "request_id", requestID,
+			"bucket", bucket, "key", key, "size", *head.ContentLength, "threshold", a.config.StreamThresholdBytes)
+		a.streamGetObject(w, r, bucket, key, rangeHeader, ifNoneMatch)
+		return
+	}
+
+	var rangeStart, rangeEnd *int64
+	if rangeOK {
+		if parsedRange.needsSize() {
+			if head != nil && head.ContentLength != nil {
+				rangeStart, rangeEnd = parsedRange.resolve(*head.ContentLength)
+			}
+			// else: the object's size couldn't be determined, so an
+			// open-ended or suffix range can't be resolved; fall through
+			// and serve the full object, which RFC 9110 permits.
+		} else {
+			rangeStart, rangeEnd = parsedRange.start, parsedRange.end
+		}
+	}
+
+	request := &batching.BatchRequest{
+		Type:        batching.TypeGetObject,
+		RequestID:   requestID,
+		BucketName:  bucket,
+		Key:         key,
+		RangeStart:  rangeStart,
+		RangeEnd:    rangeEnd,
+		IfNoneMatch: ifNoneMatch,
+	}
+
+	result, err := a.submitWithResilience(r.Context(), request, nil)
+	if err != nil {
+		if batching.IsNotModified(err) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
+		writeSubmitError(w, "get object", err)
+		return
+	}
 
-		// Marshal the response
-		jsonData, err := json.Marshal(resp)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
+	resp, ok := result.(*s3.GetObjectOutput)
+	if !ok {
+		http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	writeGetObjectHeaders(w, resp)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		slog.Error("error copying response body", "request_id", requestID, "error", err)
+	}
+}
+
+// streamGetObject fetches bucket/key directly from the object store,
+// bypassing the batcher, and streams the body straight to w as it arrives
+// rather than buffering it first.
+func (a *BatchingAgent) streamGetObject(w http.ResponseWriter, r *http.Request, bucket, key, rangeHeader, ifNoneMatch string) {
+	input := &s3.GetObjectInput{Bucket: &bucket, Key: &key}
+	if rangeHeader != "" {
+		input.Range = &rangeHeader
+	}
+	if ifNoneMatch != "" {
+		input.IfNoneMatch = &ifNoneMatch
+	}
+
+	resp, err := a.store.GetObject(r.Context(), input)
+	if err != nil {
+		if batching.IsNotModified(err) {
+			w.WriteHeader(http.StatusNotModified)
 			return
 		}
+		http.Error(w, fmt.Sprintf("Failed to get object: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	writeGetObjectHeaders(w, resp)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		slog.Error("error copying streamed response body", "bucket", bucket, "key", key, "error", err)
+	}
+}
+
+// writeGetObjectHeaders sets the headers and status code (206 if resp is a
+// range response, 200 otherwise) shared by the batched and streamed
+// handleGetObject paths.
+func writeGetObjectHeaders(w http.ResponseWriter, resp *s3.GetObjectOutput) {
+	if resp.ContentType != nil {
+		w.Header().Set("Content-Type", *resp.ContentType)
+	}
+	if resp.ContentLength != nil {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", *resp.ContentLength))
+	}
+	if resp.ETag != nil {
+		w.Header().Set("ETag", *resp.ETag)
+	}
+	if resp.LastModified != nil {
+		w.Header().Set("Last-Modified", resp.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if resp.AcceptRanges != nil {
+		w.Header().Set("Accept-Ranges", *resp.AcceptRanges)
+	}
+	if resp.ContentRange != nil {
+		w.Header().Set("Content-Range", *resp.ContentRange)
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// byteRange is a single-range "bytes=..." Range header, parsed but not
+// necessarily resolved: an open-ended ("bytes=500-") or suffix
+// ("bytes=-500") range needs the object's total size before it can become
+// concrete start/end offsets (see needsSize/resolve).
+type byteRange struct {
+	start, end   *int64 // both set: a fully-specified closed range
+	openEnded    bool   // "bytes=N-": start is set, end runs to the object's last byte
+	suffixLength *int64 // "bytes=-N": the last N bytes of the object
+}
+
+// needsSize reports whether resolve must be called (with the object's total
+// size) before start/end are usable.
+func (r *byteRange) needsSize() bool {
+	return r.openEnded || r.suffixLength != nil
+}
+
+// resolve turns an open-ended or suffix range into concrete, inclusive
+// start/end offsets against an object of the given total size.
+func (r *byteRange) resolve(size int64) (start, end *int64) {
+	switch {
+	case r.suffixLength != nil:
+		n := *r.suffixLength
+		if n > size {
+			n = size
+		}
+		s, e := size-n, size-1
+		return &s, &e
+	case r.openEnded:
+		e := size - 1
+		return r.start, &e
+	default:
+		return r.start, r.end
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(jsonData)
+// parseByteRange parses a single byte range out of a client's Range header:
+// a closed range ("bytes=500-999"), an open-ended range ("bytes=500-"), or a
+// suffix range ("bytes=-500"). Multi-range headers and anything else that
+// doesn't parse report ok=false, so the caller can fall back to serving the
+// full object, which RFC 9110 permits a server to do.
+func parseByteRange(header string) (r *byteRange, ok bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, false
+	}
+	spec := strings.TrimPrefix(header, prefix)
+	if strings.Contains(spec, ",") {
+		return nil, false
+	}
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return nil, false
+	}
 
-	case err := <-errorChan:
-		http.Error(w, fmt.Sprintf("Failed to list objects: %v", err), http.StatusInternalServerError)
+	switch {
+	case parts[0] == "" && parts[1] != "":
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n <= 0 {
+			return nil, false
+		}
+		return &byteRange{suffixLength: &n}, true
+	case parts[0] != "" && parts[1] == "":
+		s, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || s < 0 {
+			return nil, false
+		}
+		return &byteRange{start: &s, openEnded: true}, true
+	case parts[0] != "" && parts[1] != "":
+		s, errStart := strconv.ParseInt(parts[0], 10, 64)
+		e, errEnd := strconv.ParseInt(parts[1], 10, 64)
+		if errStart != nil || errEnd != nil || s > e {
+			return nil, false
+		}
+		return &byteRange{start: &s, end: &e}, true
+	default:
+		return nil, false
 	}
 }
 
-func (a *BatchingAgent) handleGetObject(w http.ResponseWriter, r *http.Request) {
+// handlePutObject uploads the request body as bucket/key. Unlike getObject,
+// the body isn't coalesced across requests; the batch window only delays it
+// alongside whatever else is in flight for the S3 queue.
+func (a *BatchingAgent) handlePutObject(w http.ResponseWriter, r *http.Request) {
 	bucket := r.URL.Query().Get("bucket")
 	if bucket == "" {
 		bucket = a.config.DefaultBucketName
@@ -222,51 +789,234 @@ func (a *BatchingAgent) handleGetObject(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resultChan := make(chan any, 1)
-	errorChan := make(chan error, 1)
+	requestID := nextRequestID()
+	slog.Info("handling putObject", "request_id", requestID, "bucket", bucket, "key", key)
+
+	// A retry needs to resend the body from the start, which an
+	// already-partially-read r.Body can't do; buffer it once so rewind can
+	// hand each attempt a fresh reader over the same bytes.
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
 
-	// Create a batch request
 	request := &batching.BatchRequest{
-		Type:       batching.TypeGetObject,
+		Type:        batching.TypePutObject,
+		RequestID:   requestID,
+		BucketName:  bucket,
+		Key:         key,
+		Body:        bytes.NewReader(data),
+		ContentType: r.Header.Get("Content-Type"),
+	}
+	rewind := func() { request.Body = bytes.NewReader(data) }
+
+	result, err := a.submitWithResilience(r.Context(), request, rewind)
+	if err != nil {
+		writeSubmitError(w, "put object", err)
+		return
+	}
+
+	resp, ok := result.(*s3.PutObjectOutput)
+	if !ok {
+		http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		return
+	}
+	if resp.ETag != nil {
+		w.Header().Set("ETag", *resp.ETag)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDeleteObject deletes a single key; the agent's S3 queue coalesces
+// many concurrent deletes against the same bucket into one DeleteObjects
+// call (see executeDeleteObjectsGroup), matching this request's key back out
+// of that call's Deleted/Errors slices.
+func (a *BatchingAgent) handleDeleteObject(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = a.config.DefaultBucketName
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing required parameter: key", http.StatusBadRequest)
+		return
+	}
+
+	requestID := nextRequestID()
+	slog.Info("handling deleteObject", "request_id", requestID, "bucket", bucket, "key", key)
+
+	request := &batching.BatchRequest{
+		Type:       batching.TypeDeleteObject,
+		RequestID:  requestID,
 		BucketName: bucket,
 		Key:        key,
-		ResultChan: resultChan,
-		ErrorChan:  errorChan,
 	}
 
-	// Submit the request
-	a.batcher.Submit(request)
+	if _, err := a.submitWithResilience(r.Context(), request, nil); err != nil {
+		writeSubmitError(w, "delete object", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
 
-	// Wait for the result
-	select {
-	case result := <-resultChan:
-		resp, ok := result.(*s3.GetObjectOutput)
-		if !ok {
-			http.Error(w, "Invalid response type", http.StatusInternalServerError)
-			return
-		}
+// handleHeadObject returns an object's metadata without its body.
+func (a *BatchingAgent) handleHeadObject(w http.ResponseWriter, r *http.Request) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		bucket = a.config.DefaultBucketName
+	}
 
-		// Set headers
-		w.Header().Set("Content-Type", *resp.ContentType)
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", resp.ContentLength))
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "Missing required parameter: key", http.StatusBadRequest)
+		return
+	}
 
-		// Copy the body to the response
-		if _, err := io.Copy(w, resp.Body); err != nil {
-			log.Printf("Error copying response body: %v", err)
-		}
-		resp.Body.Close()
+	requestID := nextRequestID()
+	slog.Info("handling headObject", "request_id", requestID, "bucket", bucket, "key", key)
 
-	case err := <-errorChan:
-		http.Error(w, fmt.Sprintf("Failed to get object: %v", err), http.StatusInternalServerError)
+	request := &batching.BatchRequest{
+		Type:       batching.TypeHeadObject,
+		RequestID:  requestID,
+		BucketName: bucket,
+		Key:        key,
+	}
+
+	result, err := a.submitWithResilience(r.Context(), request, nil)
+	if err != nil {
+		writeSubmitError(w, "head object", err)
+		return
+	}
+
+	resp, ok := result.(*s3.HeadObjectOutput)
+	if !ok {
+		http.Error(w, "Invalid response type", http.StatusInternalServerError)
+		return
+	}
+	if resp.ContentLength != nil {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", *resp.ContentLength))
+	}
+	if resp.ETag != nil {
+		w.Header().Set("ETag", *resp.ETag)
 	}
+	if resp.LastModified != nil {
+		w.Header().Set("Last-Modified", resp.LastModified.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleSendMessage fans a single SQS message into the AWSBatcher's
+// SendMessageBatch queue and waits for its entry's result.
+func (a *BatchingAgent) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		QueueURL    string `json:"queue_url"`
+		MessageBody string `json:"message_body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	queueURL := body.QueueURL
+	if queueURL == "" {
+		queueURL = a.config.SQSQueueURL
+	}
+	if queueURL == "" {
+		http.Error(w, "Missing required parameter: queue_url", http.StatusBadRequest)
+		return
+	}
+
+	requestID := nextRequestID()
+	slog.Info("handling sendMessage", "request_id", requestID, "queue_url", queueURL)
+
+	request := &batching.BatchRequest{
+		Type:        batching.TypeSendMessage,
+		RequestID:   requestID,
+		QueueURL:    queueURL,
+		MessageBody: body.MessageBody,
+	}
+
+	result, err := a.submitWithResilience(r.Context(), request, nil)
+	if err != nil {
+		writeSubmitError(w, "send message", err)
+		return
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// handlePutRecord fans a single Kinesis record into the AWSBatcher's
+// PutRecords queue and waits for its entry's result.
+func (a *BatchingAgent) handlePutRecord(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		StreamName   string `json:"stream_name"`
+		PartitionKey string `json:"partition_key"`
+		Data         []byte `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	streamName := body.StreamName
+	if streamName == "" {
+		streamName = a.config.KinesisStreamName
+	}
+	if streamName == "" {
+		http.Error(w, "Missing required parameter: stream_name", http.StatusBadRequest)
+		return
+	}
+
+	requestID := nextRequestID()
+	slog.Info("handling putRecord", "request_id", requestID, "stream_name", streamName)
+
+	request := &batching.BatchRequest{
+		Type:         batching.TypePutRecord,
+		RequestID:    requestID,
+		StreamName:   streamName,
+		PartitionKey: body.PartitionKey,
+		Data:         body.Data,
+	}
+
+	result, err := a.submitWithResilience(r.Context(), request, nil)
+	if err != nil {
+		writeSubmitError(w, "put record", err)
+		return
+	}
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// debugConfigResponse wraps Configuration with each queue's batch window as
+// it stands right now; under --adaptive-batching that differs from the
+// configured *BatchWindow fields above, which are only the starting point.
+type debugConfigResponse struct {
+	Configuration
+	CurrentBatchWindows map[string]time.Duration `json:"current_batch_windows"`
 }
 
 func (a *BatchingAgent) handleDebugConfig(w http.ResponseWriter, r *http.Request) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Marshal the configuration
-	jsonData, err := json.Marshal(a.config)
+	jsonData, err := json.Marshal(debugConfigResponse{
+		Configuration:       a.config,
+		CurrentBatchWindows: a.batcher.CurrentWindows(),
+	})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to marshal configuration: %v", err), http.StatusInternalServerError)
 		return
@@ -276,26 +1026,104 @@ func (a *BatchingAgent) handleDebugConfig(w http.ResponseWriter, r *http.Request
 	w.Write(jsonData)
 }
 
+// handleDebugBreakers reports every request type's current circuit breaker
+// state, alongside /debug/config's view of the batch windows.
+func (a *BatchingAgent) handleDebugBreakers(w http.ResponseWriter, r *http.Request) {
+	jsonData, err := json.Marshal(a.breaker.Snapshot())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal breaker state: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
+// handlePrefetchStatus reports every prefetched model's current state, for
+// callers to poll before assuming a model is available on disk.
+func (a *BatchingAgent) handlePrefetchStatus(w http.ResponseWriter, r *http.Request) {
+	jsonData, err := json.Marshal(a.prefetch.Status())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal prefetch status: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
+
 func main() {
 	// Parse command-line flags
 	port := flag.Int("port", 8080, "Port to listen on")
 	batchingEnabled := flag.Bool("batching", true, "Enable request batching")
 	batchWindow := flag.Duration("batch-window", 100*time.Millisecond, "Batch window duration")
 	maxBatchSize := flag.Int("max-batch-size", 10, "Maximum batch size")
+	adaptiveBatching := flag.Bool("adaptive-batching", false, "Derive each queue's batch window from observed request rate instead of using a fixed window")
+	minBatchWindow := flag.Duration("min-batch-window", 10*time.Millisecond, "Lower bound on the batch window in adaptive mode")
+	maxBatchWindow := flag.Duration("max-batch-window", 500*time.Millisecond, "Upper bound on the batch window in adaptive mode")
+	maxBufferBytes := flag.Int64("max-buffer-bytes", 64*1024*1024, "Cap on how much of a coalesced GetObject body is buffered in memory before spilling to a temp file")
+	sqsQueueURL := flag.String("sqs-queue-url", "", "Default SQS queue URL for sendMessage requests that don't specify one")
+	sqsBatchWindow := flag.Duration("sqs-batch-window", 50*time.Millisecond, "SQS SendMessageBatch fan-in window")
+	sqsMaxBatchSize := flag.Int("sqs-max-batch-size", 10, "Maximum messages per SendMessageBatch call")
+	kinesisStreamName := flag.String("kinesis-stream-name", "", "Default Kinesis stream name for putRecord requests that don't specify one")
+	kinesisBatchWindow := flag.Duration("kinesis-batch-window", 50*time.Millisecond, "Kinesis PutRecords fan-in window")
+	kinesisMaxBatchSize := flag.Int("kinesis-max-batch-size", 100, "Maximum records per PutRecords call")
 	debugMode := flag.Bool("debug", false, "Enable debug mode")
 	awsRegion := flag.String("aws-region", "us-east-1", "AWS region")
 	defaultBucketName := flag.String("default-bucket", "", "Default S3 bucket name")
+	backend := flag.String("backend", "s3", "Object store backend: s3, gcs, azure, or minio")
+	minioEndpoint := flag.String("minio-endpoint", "", "Base endpoint for the minio backend (MinIO, R2, or any S3-compatible server)")
+	minioUsePathStyle := flag.Bool("minio-use-path-style", true, "Address buckets path-style against the minio backend's endpoint")
+	azureStorageAccount := flag.String("azure-storage-account", "", "Storage account name for the azure backend")
+	eventSink := flag.String("event-sink", "", "Destination for per-request completion events emitted after each S3 batch: \"\" (disabled) or \"sqs\"")
+	eventSinkSQSQueueURL := flag.String("event-sink-sqs-queue-url", "", "SQS queue URL for the \"sqs\" event sink")
+	maxRetries := flag.Int("max-retries", 2, "Maximum retries for a request that fails with a transient (throttling/5xx) error")
+	retryBaseDelay := flag.Duration("retry-base-delay", 50*time.Millisecond, "Base delay for exponential backoff between retries")
+	breakerThreshold := flag.Int("breaker-threshold", 5, "Consecutive failures of one request type before its circuit breaker trips open")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "How long a tripped circuit breaker stays open before allowing a trial request through")
+	prefetchConfig := flag.String("prefetch-config", "", "Path to a JSON config file listing {name, bucket, key} entries to keep pulled into --prefetch-dir; \"\" disables the prefetch subsystem")
+	prefetchDir := flag.String("prefetch-dir", "/tmp/prefetch", "Local directory prefetched objects are downloaded into")
+	prefetchWorkers := flag.Int("prefetch-workers", 4, "Maximum concurrent prefetch downloads")
+	streamThresholdBytes := flag.Int64("stream-threshold-bytes", 0, "Objects larger than this bypass the batcher and stream directly from the object store; 0 disables the check")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "OTLP/gRPC collector endpoint for distributed tracing; \"\" disables tracing")
+	metricsAddr := flag.String("metrics-addr", "", "Serve /metrics on this address instead of the main API port; \"\" mounts /metrics on the main router")
 	flag.Parse()
 
 	// Create configuration
 	config := Configuration{
-		Port:              *port,
-		BatchingEnabled:   *batchingEnabled,
-		BatchWindow:       *batchWindow,
-		MaxBatchSize:      *maxBatchSize,
-		DebugMode:         *debugMode,
-		AwsRegion:         *awsRegion,
-		DefaultBucketName: *defaultBucketName,
+		Port:                 *port,
+		BatchingEnabled:      *batchingEnabled,
+		BatchWindow:          *batchWindow,
+		MaxBatchSize:         *maxBatchSize,
+		AdaptiveBatching:     *adaptiveBatching,
+		MinBatchWindow:       *minBatchWindow,
+		MaxBatchWindow:       *maxBatchWindow,
+		MaxBufferBytes:       *maxBufferBytes,
+		DebugMode:            *debugMode,
+		AwsRegion:            *awsRegion,
+		DefaultBucketName:    *defaultBucketName,
+		Backend:              *backend,
+		MinIOEndpoint:        *minioEndpoint,
+		MinIOUsePathStyle:    *minioUsePathStyle,
+		AzureStorageAccount:  *azureStorageAccount,
+		SQSQueueURL:          *sqsQueueURL,
+		SQSBatchWindow:       *sqsBatchWindow,
+		SQSMaxBatchSize:      *sqsMaxBatchSize,
+		KinesisStreamName:    *kinesisStreamName,
+		KinesisBatchWindow:   *kinesisBatchWindow,
+		KinesisMaxBatchSize:  *kinesisMaxBatchSize,
+		EventSink:            *eventSink,
+		EventSinkSQSQueueURL: *eventSinkSQSQueueURL,
+		MaxRetries:           *maxRetries,
+		RetryBaseDelay:       *retryBaseDelay,
+		BreakerThreshold:     *breakerThreshold,
+		BreakerCooldown:      *breakerCooldown,
+		PrefetchConfig:       *prefetchConfig,
+		PrefetchDir:          *prefetchDir,
+		PrefetchWorkers:      *prefetchWorkers,
+		StreamThresholdBytes: *streamThresholdBytes,
+		OTLPEndpoint:         *otlpEndpoint,
+		MetricsAddr:          *metricsAddr,
 	}
 
 	// Create and start agent
@@ -321,4 +1149,4 @@ func main() {
 	// Shutdown the agent
 	agent.Shutdown(ctx)
 	log.Println("Shutdown complete")
-} 
\ No newline at end of file
+}
@@ -1,109 +1,421 @@
 package batching
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	kinesistypes "github.com/aws/aws-sdk-go-v2/service/kinesis/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/sinks"
 )
 
+// tracer emits the spans described in the pkg/tracing package doc: one per
+// batch dispatch, linked back to every request folded into it.
+var tracer = otel.Tracer("github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/batching")
+
 // Request types
 const (
-	TypeGetObject = "GetObject"
-	TypeListObjects = "ListObjects"
-	TypeListBuckets = "ListBuckets"
+	TypeGetObject    = "GetObject"
+	TypeListObjects  = "ListObjects"
+	TypeListBuckets  = "ListBuckets"
+	TypePutObject    = "PutObject"
+	TypeDeleteObject = "DeleteObject" // grouped into a bulk DeleteObjects call
+	TypeHeadObject   = "HeadObject"
+	TypeSendMessage  = "SendMessage" // SQS SendMessageBatch
+	TypePutRecord    = "PutRecord"   // Kinesis PutRecords
+)
+
+// SQS SendMessageBatch, Kinesis PutRecords, and S3 DeleteObjects all cap how
+// many entries a single API call can carry; AWSBatcher chunks batches that
+// exceed the relevant limit into multiple calls.
+const (
+	sqsMaxBatchMessages    = 10
+	sqsMaxBatchBytes       = 256 * 1024
+	kinesisMaxBatchRecords = 500
+	kinesisMaxBatchBytes   = 5 * 1024 * 1024
+	s3MaxDeleteObjectKeys  = 1000
 )
 
-// BatchRequest represents a generic S3 request that can be batched
+// BatchRequest represents a generic AWS request that can be batched
 type BatchRequest struct {
-	Type        string      // Type of request (GetObject, ListObjects, etc.)
-	BucketName  string      // S3 bucket name
-	Key         string      // For GetObject requests
-	Prefix      string      // For ListObjects requests
-	MaxKeys     int32       // For ListObjects requests
-	ResultChan  chan any    // Channel to deliver result
-	ErrorChan   chan error  // Channel to deliver errors
-}
-
-// S3Batcher handles batching S3 requests
-type S3Batcher struct {
-	client          *s3.Client
-	enabled         bool
-	batchWindow     time.Duration
-	maxBatchSize    int
-	batchWindowChan chan struct{}
-	pendingRequests chan *BatchRequest
-	mu              sync.Mutex
-	wg              sync.WaitGroup
-}
-
-// NewS3Batcher creates a new S3 batcher
-func NewS3Batcher(client *s3.Client, enabled bool, batchWindow time.Duration, maxBatchSize int) *S3Batcher {
-	batcher := &S3Batcher{
-		client:          client,
-		enabled:         enabled,
-		batchWindow:     batchWindow,
-		maxBatchSize:    maxBatchSize,
-		batchWindowChan: make(chan struct{}),
-		pendingRequests: make(chan *BatchRequest, maxBatchSize*10), // Buffer to handle spikes
+	Type       string     // Type of request (GetObject, ListObjects, SendMessage, etc.)
+	RequestID  string     // Caller-assigned id, logged at every stage so an operator can trace one HTTP request through submission, grouping, and the eventual backend call
+	BucketName string     // S3 bucket name
+	Key        string     // For GetObject requests
+	Prefix     string     // For ListObjects requests
+	MaxKeys    int32      // For ListObjects requests
+	ResultChan chan any   // Channel to deliver result
+	ErrorChan  chan error // Channel to deliver errors
+
+	// RangeStart/RangeEnd, for GetObject requests, request an inclusive byte
+	// range ([RangeStart, RangeEnd]) instead of the whole object. Leave both
+	// nil to fetch the full object. When several requests for the same
+	// bucket:key land in one batch, executeGroupedRequests issues a single
+	// GetObject covering the union of their ranges (or the full object if
+	// any of them omits a range) and slices the buffered body back out per
+	// request, rather than one S3 call per request.
+	RangeStart *int64
+	RangeEnd   *int64
+
+	// IfNoneMatch, for GetObject requests, is forwarded as the conditional
+	// If-None-Match header: S3 returns a 304 Not Modified error (see
+	// IsNotModified) instead of the body when it matches the object's
+	// current ETag. "" means no condition. Since this can't be merged the
+	// way RangeStart/RangeEnd are, requests land in the same coalesced group
+	// only when their IfNoneMatch values are equal (see processS3Batch).
+	IfNoneMatch string
+
+	// QueueURL/MessageBody are for SendMessage (SQS) requests. Unlike the S3
+	// GetObject grouping above, these are true fan-in batches: pending
+	// SendMessage requests are packed into one SendMessageBatch call (up to
+	// sqsMaxBatchMessages/sqsMaxBatchBytes) and demultiplexed back out by id.
+	QueueURL    string
+	MessageBody string
+
+	// StreamName/PartitionKey/Data are for PutRecord (Kinesis) requests,
+	// fanned into PutRecords calls (up to kinesisMaxBatchRecords/
+	// kinesisMaxBatchBytes) the same way SendMessage requests are.
+	StreamName   string
+	PartitionKey string
+	Data         []byte
+
+	// Body/ContentType are for PutObject requests; the object is uploaded
+	// through the ObjectStore's PutObject (manager.Uploader for the S3
+	// backend), which multiparts large bodies automatically.
+	Body        io.Reader
+	ContentType string
+
+	// submittedAt is set by Submit and read back when the request's batch
+	// dispatches, to observe Metrics.BatchWait (how long it sat in the
+	// queue before processing started).
+	submittedAt time.Time
+
+	// spanContext is the caller's trace span at submission time (captured by
+	// Submit from ctx), linked into the batch-dispatch span so every request
+	// folded into a batch shares a visible parent with its siblings (see
+	// batchQueue.loop).
+	spanContext trace.SpanContext
+}
+
+// defaultMaxBufferBytes is the default cap on how much of a coalesced
+// GetObject body is buffered in memory (per executeGroupedRequests call)
+// before spilling to a temp file.
+const defaultMaxBufferBytes = 64 * 1024 * 1024
+
+// QueueConfig is the batch window and max batch size for one of an
+// AWSBatcher's fan-in queues (S3, SQS, or Kinesis); each queue runs
+// independently of the others.
+type QueueConfig struct {
+	BatchWindow  time.Duration
+	MaxBatchSize int
+
+	// Adaptive, when true, ignores BatchWindow after the first batch and
+	// instead derives the window from an EWMA of observed inter-arrival
+	// times, clamped to [MinBatchWindow, MaxBatchWindow]. BatchWindow still
+	// governs the very first batch, before any arrivals have been observed.
+	Adaptive       bool
+	MinBatchWindow time.Duration
+	MaxBatchWindow time.Duration
+}
+
+// AWSBatcher fans in requests against several AWS services, each through
+// its own batchQueue: S3 GetObject/ListObjects/ListBuckets (deduplicated by
+// bucket:key), SQS SendMessageBatch, and Kinesis PutRecords (true fan-in,
+// packing pending requests into one API call up to the service's
+// size/count limit).
+type AWSBatcher struct {
+	store          ObjectStore
+	sqsClient      *sqs.Client
+	kinesisClient  *kinesis.Client
+	maxBufferBytes int64
+	metrics        *Metrics
+
+	// sink, if non-nil, receives one sinks.Event per completed S3 request
+	// whenever a batch finishes processing (see processS3Batch/emitEvents).
+	// Optional: nil disables event emission entirely. emitEvents never calls
+	// sink.Emit directly: it hands events to sinkEvents, which runSink drains
+	// on its own goroutine, so a slow or throttled sink can't stall a
+	// batchQueue's loop (see sinkQueueDepth).
+	sink       sinks.Sink
+	sinkEvents chan []sinks.Event
+	sinkDone   chan struct{}
+
+	s3Queue      *batchQueue
+	sqsQueue     *batchQueue
+	kinesisQueue *batchQueue
+}
+
+// sinkQueueDepth bounds how many completed batches' worth of events can be
+// queued for sink.Emit before emitEvents starts dropping them (logging when
+// it does). Sized generously since each entry is small (one sinks.Event per
+// request in a batch); a sink that's permanently behind should lose events
+// rather than back-pressure the batching pipeline.
+const sinkQueueDepth = 256
+
+// NewAWSBatcher creates an AWSBatcher. store serves GetObject/ListObjects/
+// ListBuckets and may be backed by AWS S3, GCS, Azure Blob, or an
+// S3-compatible endpoint (see NewS3Store/NewGCSStore/NewAzureStore/
+// NewMinIOStore) — the batching/coalescing logic below doesn't depend on
+// which. s3Config, sqsConfig, and kinesisConfig each govern that service's
+// own batch window and max batch size, independently of the others.
+// maxBufferBytes caps how much of a coalesced GetObject body is buffered in
+// memory before spilling to a temp file; 0 uses defaultMaxBufferBytes. sink
+// may be nil to disable per-request event emission (see pkg/sinks).
+func NewAWSBatcher(store ObjectStore, sqsClient *sqs.Client, kinesisClient *kinesis.Client, enabled bool, s3Config, sqsConfig, kinesisConfig QueueConfig, maxBufferBytes int64, sink sinks.Sink) *AWSBatcher {
+	if maxBufferBytes <= 0 {
+		maxBufferBytes = defaultMaxBufferBytes
+	}
+
+	b := &AWSBatcher{
+		store:          store,
+		sqsClient:      sqsClient,
+		kinesisClient:  kinesisClient,
+		maxBufferBytes: maxBufferBytes,
+		metrics:        NewMetrics(),
+		sink:           sink,
+	}
+
+	if sink != nil {
+		b.sinkEvents = make(chan []sinks.Event, sinkQueueDepth)
+		b.sinkDone = make(chan struct{})
+		go b.runSink()
+	}
+
+	b.s3Queue = newBatchQueue("s3", enabled, s3Config, b.metrics, b.processS3Batch)
+	b.sqsQueue = newBatchQueue("sqs", enabled, sqsConfig, b.metrics, b.processSQSBatch)
+	b.kinesisQueue = newBatchQueue("kinesis", enabled, kinesisConfig, b.metrics, b.processKinesisBatch)
+
+	return b
+}
+
+// runSink drains sinkEvents and forwards each batch to sink.Emit, one at a
+// time, until sinkEvents is closed (see Shutdown). Running on its own
+// goroutine is what keeps a slow sink off the batchQueue.loop hot path.
+func (b *AWSBatcher) runSink() {
+	defer close(b.sinkDone)
+	for events := range b.sinkEvents {
+		b.sink.Emit(context.Background(), events)
+	}
+}
+
+// Metrics returns the Prometheus collectors for this batcher, so callers can
+// mount Metrics.Handler() (e.g. at /metrics) for scraping.
+func (b *AWSBatcher) Metrics() *Metrics {
+	return b.metrics
+}
+
+// CurrentWindows returns each queue's batch window as it stands right now,
+// keyed by queue name ("s3", "sqs", "kinesis"). For a fixed-window queue
+// this is just its configured BatchWindow; for an adaptive one it's the
+// most recently computed EWMA-derived window. Intended for exposing live
+// adaptive state through an endpoint like /debug/config.
+func (b *AWSBatcher) CurrentWindows() map[string]time.Duration {
+	return map[string]time.Duration{
+		"s3":      b.s3Queue.currentWindow(),
+		"sqs":     b.sqsQueue.currentWindow(),
+		"kinesis": b.kinesisQueue.currentWindow(),
+	}
+}
+
+// Submit routes a request to the batching queue for its service. ctx's span
+// (if any) is captured on request and linked into the batch-dispatch span
+// once the request's batch is processed (see batchQueue.loop).
+func (b *AWSBatcher) Submit(ctx context.Context, request *BatchRequest) {
+	request.submittedAt = time.Now()
+	request.spanContext = trace.SpanContextFromContext(ctx)
+	b.metrics.Inflight.WithLabelValues(request.Type).Inc()
+
+	switch request.Type {
+	case TypeSendMessage:
+		b.sqsQueue.submit(request)
+	case TypePutRecord:
+		b.kinesisQueue.submit(request)
+	default:
+		b.s3Queue.submit(request)
+	}
+}
+
+// Shutdown stops every queue and waits for all requests to finish.
+func (b *AWSBatcher) Shutdown() {
+	b.s3Queue.shutdown()
+	b.sqsQueue.shutdown()
+	b.kinesisQueue.shutdown()
+
+	if b.sinkEvents != nil {
+		close(b.sinkEvents)
+		<-b.sinkDone
+	}
+}
+
+// batchQueue batches one kind of AWS request: it collects requests off
+// pending until maxBatch is reached or window elapses since the first
+// request in the batch, then hands the batch to process.
+type batchQueue struct {
+	name     string // "s3", "sqs", or "kinesis"; the metrics label and slog field for this queue
+	enabled  bool
+	config   QueueConfig
+	maxBatch int
+	pending  chan *BatchRequest
+	process  func(ctx context.Context, batch []*BatchRequest)
+	metrics  *Metrics
+	wg       sync.WaitGroup
+
+	// windowMu guards the EWMA state consulted by currentWindow when config.
+	// Adaptive is set; submit updates it on every arrival, and loop reads it
+	// once per batch. Unused (and uncontended) for fixed-window queues.
+	windowMu    sync.Mutex
+	lastArrival time.Time
+	avgInterval time.Duration
+}
+
+// ewmaAlpha weights how quickly an adaptive queue's avgInterval reacts to a
+// new inter-arrival sample vs. its running history.
+const ewmaAlpha = 0.2
+
+// newBatchQueue creates a batchQueue and, if enabled, starts its collection
+// loop. When disabled, submit executes every request immediately instead of
+// queuing it.
+func newBatchQueue(name string, enabled bool, config QueueConfig, metrics *Metrics, process func(context.Context, []*BatchRequest)) *batchQueue {
+	q := &batchQueue{
+		name:     name,
+		enabled:  enabled,
+		config:   config,
+		maxBatch: config.MaxBatchSize,
+		pending:  make(chan *BatchRequest, config.MaxBatchSize*10), // Buffer to handle spikes
+		process:  process,
+		metrics:  metrics,
 	}
 
 	if enabled {
-		batcher.wg.Add(1)
-		go batcher.processRequestsLoop()
+		q.wg.Add(1)
+		go q.loop()
 	}
 
-	return batcher
+	return q
 }
 
-// Submit adds a request to the batching queue
-func (b *S3Batcher) Submit(request *BatchRequest) {
-	if !b.enabled {
-		// If batching is disabled, execute the request immediately
-		b.executeGroupedRequests(context.Background(), []*BatchRequest{request})
+// submit adds a request to the queue, or executes it immediately if the
+// queue is disabled.
+func (q *batchQueue) submit(request *BatchRequest) {
+	slog.Info("batching: request submitted", "request_id", request.RequestID, "type", request.Type, "queue", q.name)
+
+	if !q.enabled {
+		ctx := context.Background()
+		if request.spanContext.IsValid() {
+			ctx = trace.ContextWithSpanContext(ctx, request.spanContext)
+		}
+		q.process(ctx, []*BatchRequest{request})
 		return
 	}
 
-	// Submit to the batching queue
-	b.pendingRequests <- request
+	if q.config.Adaptive {
+		q.recordArrival(time.Now())
+	}
+
+	q.pending <- request
+	q.metrics.QueueDepth.WithLabelValues(q.name).Set(float64(len(q.pending)))
+}
+
+// recordArrival folds the interval since the previous arrival into the
+// queue's EWMA of inter-arrival times.
+func (q *batchQueue) recordArrival(now time.Time) {
+	q.windowMu.Lock()
+	defer q.windowMu.Unlock()
+
+	if !q.lastArrival.IsZero() {
+		sample := now.Sub(q.lastArrival)
+		if q.avgInterval == 0 {
+			q.avgInterval = sample
+		} else {
+			q.avgInterval = time.Duration(ewmaAlpha*float64(sample) + (1-ewmaAlpha)*float64(q.avgInterval))
+		}
+	}
+	q.lastArrival = now
+}
+
+// currentWindow returns the batch window to use for the next batch: the
+// fixed config.BatchWindow for a non-adaptive queue, or an EWMA-derived
+// window clamped to [MinBatchWindow, MaxBatchWindow] for an adaptive one.
+// Sized so that, on average, a batch fills up (maxBatch arrivals) just
+// before the timer would fire.
+func (q *batchQueue) currentWindow() time.Duration {
+	if !q.config.Adaptive {
+		return q.config.BatchWindow
+	}
+
+	q.windowMu.Lock()
+	avgInterval := q.avgInterval
+	q.windowMu.Unlock()
+
+	if avgInterval == 0 {
+		return q.config.BatchWindow
+	}
+
+	window := avgInterval * time.Duration(q.maxBatch)
+	if window < q.config.MinBatchWindow {
+		window = q.config.MinBatchWindow
+	}
+	if window > q.config.MaxBatchWindow {
+		window = q.config.MaxBatchWindow
+	}
+	return window
 }
 
-// Shutdown stops the batcher and waits for all requests to finish
-func (b *S3Batcher) Shutdown() {
-	if b.enabled {
-		close(b.pendingRequests)
-		b.wg.Wait()
+// shutdown stops the queue and waits for its collection loop to finish.
+func (q *batchQueue) shutdown() {
+	if q.enabled {
+		close(q.pending)
+		q.wg.Wait()
 	}
 }
 
-// processRequestsLoop processes batches of requests
-func (b *S3Batcher) processRequestsLoop() {
-	defer b.wg.Done()
+// loop collects batches of requests until maxBatch is reached or window
+// elapses, then hands each batch to process.
+func (q *batchQueue) loop() {
+	defer q.wg.Done()
 
 	for {
 		// Create a new batch
-		batch := make([]*BatchRequest, 0, b.maxBatchSize)
-		
+		batch := make([]*BatchRequest, 0, q.maxBatch)
+
 		// Wait for first request or exit if channel is closed
-		request, ok := <-b.pendingRequests
+		request, ok := <-q.pending
 		if !ok {
 			// Channel closed, exit
 			return
 		}
-		
+
 		batch = append(batch, request)
-		
+
 		// Set timer for batch window
-		timer := time.NewTimer(b.batchWindow)
+		window := q.currentWindow()
+		q.metrics.Window.WithLabelValues(q.name).Set(window.Seconds())
+		timer := time.NewTimer(window)
 
 		// Collect requests until batch is full or window expires
+		timedOut := false
 	collectLoop:
-		for len(batch) < b.maxBatchSize {
+		for len(batch) < q.maxBatch {
 			select {
-			case request, ok := <-b.pendingRequests:
+			case request, ok := <-q.pending:
 				if !ok {
 					// Channel closed
 					break collectLoop
@@ -111,6 +423,7 @@ func (b *S3Batcher) processRequestsLoop() {
 				batch = append(batch, request)
 			case <-timer.C:
 				// Batch window expired
+				timedOut = true
 				break collectLoop
 			}
 		}
@@ -124,127 +437,702 @@ func (b *S3Batcher) processRequestsLoop() {
 			}
 		}
 
-		// Process the batch
-		b.processBatch(batch)
+		reason := "size"
+		if timedOut {
+			reason = "timer"
+		}
+		q.metrics.BatchSize.WithLabelValues(q.name).Observe(float64(len(batch)))
+		q.metrics.BatchFillRatio.WithLabelValues(q.name).Observe(float64(len(batch)) / float64(q.maxBatch))
+		q.metrics.BatchCloseReason.WithLabelValues(q.name, reason).Inc()
+		q.metrics.QueueDepth.WithLabelValues(q.name).Set(float64(len(q.pending)))
+		links := make([]trace.Link, 0, len(batch))
+		for _, request := range batch {
+			q.metrics.BatchWait.WithLabelValues(q.name).Observe(time.Since(request.submittedAt).Seconds())
+			if request.spanContext.IsValid() {
+				links = append(links, trace.Link{SpanContext: request.spanContext})
+			}
+		}
+
+		// Process the batch, in a span linking back to every request's own
+		// span so batched siblings show up as sharing a dispatch, not as
+		// unrelated work.
+		ctx, span := tracer.Start(context.Background(), "batching.dispatch_batch", trace.WithLinks(links...),
+			trace.WithAttributes(attribute.String("queue", q.name), attribute.Int("batch.size", len(batch))))
+		q.process(ctx, batch)
+		span.End()
 	}
 }
 
-// processBatch processes a batch of requests
-func (b *S3Batcher) processBatch(batch []*BatchRequest) {
+// processS3Batch processes a batch of S3 requests
+func (b *AWSBatcher) processS3Batch(ctx context.Context, batch []*BatchRequest) {
 	if len(batch) == 0 {
 		return
 	}
 
-	ctx := context.Background()
-
 	// Group requests by type and parameters
 	getObjectRequests := make(map[string][]*BatchRequest)
 	listObjectsRequests := make(map[string][]*BatchRequest)
 	listBucketsRequests := make([]*BatchRequest, 0)
+	headObjectRequests := make(map[string][]*BatchRequest)
+	deleteObjectRequests := make(map[string][]*BatchRequest) // keyed by bucket: every key in a bucket goes into one DeleteObjects call
+
+	// events collects one sinks.Event per request completed while processing
+	// this batch; emitEvents forwards them to b.sink (if configured) once
+	// the whole batch is done, rather than one sink call per request.
+	var events []sinks.Event
 
 	for _, request := range batch {
 		switch request.Type {
 		case TypeGetObject:
-			key := fmt.Sprintf("%s:%s", request.BucketName, request.Key)
+			key := fmt.Sprintf("%s:%s:%s", request.BucketName, request.Key, request.IfNoneMatch)
 			getObjectRequests[key] = append(getObjectRequests[key], request)
 		case TypeListObjects:
 			key := fmt.Sprintf("%s:%s:%d", request.BucketName, request.Prefix, request.MaxKeys)
 			listObjectsRequests[key] = append(listObjectsRequests[key], request)
 		case TypeListBuckets:
 			listBucketsRequests = append(listBucketsRequests, request)
+		case TypeHeadObject:
+			key := fmt.Sprintf("%s:%s", request.BucketName, request.Key)
+			headObjectRequests[key] = append(headObjectRequests[key], request)
+		case TypeDeleteObject:
+			deleteObjectRequests[request.BucketName] = append(deleteObjectRequests[request.BucketName], request)
+		case TypePutObject:
+			// PutObject bodies aren't coalesced across requests; each still
+			// runs through executeGroupedRequests so it's covered by the
+			// batch window's delay (and shares the batch's metrics) like
+			// every other S3 call.
+			b.executeGroupedRequests(ctx, []*BatchRequest{request}, &events)
 		default:
 			// Unknown request type, execute immediately
-			b.executeGroupedRequests(ctx, []*BatchRequest{request})
+			b.executeGroupedRequests(ctx, []*BatchRequest{request}, &events)
 		}
 	}
 
 	// Process grouped GetObject requests
 	for _, requests := range getObjectRequests {
-		b.executeGroupedRequests(ctx, requests)
+		b.executeGroupedRequests(ctx, requests, &events)
 	}
 
 	// Process grouped ListObjects requests
 	for _, requests := range listObjectsRequests {
-		b.executeGroupedRequests(ctx, requests)
+		b.executeGroupedRequests(ctx, requests, &events)
+	}
+
+	// Process grouped HeadObject requests
+	for _, requests := range headObjectRequests {
+		b.executeGroupedRequests(ctx, requests, &events)
+	}
+
+	// Process grouped DeleteObject requests, one DeleteObjects call per bucket
+	for _, requests := range deleteObjectRequests {
+		b.executeDeleteObjectsGroup(ctx, requests, &events)
 	}
-	
+
 	// Process ListBuckets requests (if any)
 	if len(listBucketsRequests) > 0 {
-		b.executeGroupedRequests(ctx, listBucketsRequests)
+		b.executeGroupedRequests(ctx, listBucketsRequests, &events)
 	}
+
+	b.emitEvents(events)
 }
 
-// executeGroupedRequests executes the first request and distributes the result to all requests in the group
-func (b *S3Batcher) executeGroupedRequests(ctx context.Context, requests []*BatchRequest) {
+// emitEvents hands events to runSink, if a sink is configured. Sink delivery
+// is best-effort: AWSBatcher never blocks request completion or a
+// batchQueue's loop on it, and a nil sink (the default) makes this a no-op.
+// If runSink has fallen behind (sinkEvents is full), events is dropped and
+// logged rather than backing up into the batching pipeline.
+func (b *AWSBatcher) emitEvents(events []sinks.Event) {
+	if b.sink == nil || len(events) == 0 {
+		return
+	}
+	select {
+	case b.sinkEvents <- events:
+	default:
+		slog.Warn("batching: dropping sink events, sink queue full", "count", len(events))
+	}
+}
+
+// recordCompletion records one completed BatchRequest's outcome: it
+// increments RequestsTotal and decrements Inflight (incremented back in
+// Submit), and appends a sinks.Event to events for emitEvents to forward
+// once the whole batch finishes. events is nil-safe so callers that don't
+// track events (there are none today, but future direct callers of these
+// execute* helpers shouldn't have to) can pass nil.
+func (b *AWSBatcher) recordCompletion(events *[]sinks.Event, reqType, bucket, key string, start time.Time, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	b.metrics.RequestsTotal.WithLabelValues(reqType, status).Inc()
+	b.metrics.Inflight.WithLabelValues(reqType).Dec()
+
+	if events == nil {
+		return
+	}
+	event := sinks.Event{Type: reqType, Bucket: bucket, Key: key, Latency: time.Since(start)}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	*events = append(*events, event)
+}
+
+// executeGroupedRequests executes the first request and distributes the
+// result to all requests in the group. events collects one sinks.Event per
+// request completed here, for the caller to forward via emitEvents once the
+// whole batch is done.
+func (b *AWSBatcher) executeGroupedRequests(ctx context.Context, requests []*BatchRequest, events *[]sinks.Event) {
 	if len(requests) == 0 {
 		return
 	}
-	
+
 	// Execute the first request to get the result
 	firstRequest := requests[0]
-	log.Printf("Executing request of type: %s for a group of %d requests", firstRequest.Type, len(requests))
-	
+	slog.Info("batching: executing grouped request", "type", firstRequest.Type, "group_size", len(requests), "request_ids", requestIDs(requests))
+
 	switch firstRequest.Type {
 	case TypeGetObject:
-		input := &s3.GetObjectInput{
-			Bucket: &firstRequest.BucketName,
-			Key:    &firstRequest.Key,
+		b.executeGetObjectGroup(ctx, requests, events)
+
+	case TypeListObjects:
+		maxKeys := firstRequest.MaxKeys
+		input := &s3.ListObjectsV2Input{
+			Bucket:  &firstRequest.BucketName,
+			Prefix:  &firstRequest.Prefix,
+			MaxKeys: &maxKeys,
 		}
-		
-		result, err := b.client.GetObject(ctx, input)
+
+		b.metrics.CoalescedRequests.WithLabelValues(TypeListObjects).Add(float64(len(requests)))
+		b.metrics.CoalescedCalls.WithLabelValues(TypeListObjects).Inc()
+		start := time.Now()
+		result, err := b.store.ListObjects(ctx, input)
+		b.metrics.observeCall(TypeListObjects, start)
 		if err != nil {
+			b.metrics.recordErrors(TypeListObjects, err, len(requests))
 			// Send error to all requests
 			for _, req := range requests {
 				req.ErrorChan <- err
+				b.recordCompletion(events, TypeListObjects, req.BucketName, req.Prefix, start, err)
 			}
 		} else {
 			// Send result to all requests
 			for _, req := range requests {
 				req.ResultChan <- result
+				b.recordCompletion(events, TypeListObjects, req.BucketName, req.Prefix, start, nil)
 			}
 		}
-		
-	case TypeListObjects:
-		maxKeys := firstRequest.MaxKeys
-		input := &s3.ListObjectsV2Input{
-			Bucket:  &firstRequest.BucketName,
-			Prefix:  &firstRequest.Prefix,
-			MaxKeys: &maxKeys,
-		}
-		
-		result, err := b.client.ListObjectsV2(ctx, input)
+
+	case TypeListBuckets:
+		input := &s3.ListBucketsInput{}
+
+		b.metrics.CoalescedRequests.WithLabelValues(TypeListBuckets).Add(float64(len(requests)))
+		b.metrics.CoalescedCalls.WithLabelValues(TypeListBuckets).Inc()
+		start := time.Now()
+		result, err := b.store.ListBuckets(ctx, input)
+		b.metrics.observeCall(TypeListBuckets, start)
 		if err != nil {
+			b.metrics.recordErrors(TypeListBuckets, err, len(requests))
 			// Send error to all requests
 			for _, req := range requests {
 				req.ErrorChan <- err
+				b.recordCompletion(events, TypeListBuckets, "", "", start, err)
 			}
 		} else {
 			// Send result to all requests
 			for _, req := range requests {
 				req.ResultChan <- result
+				b.recordCompletion(events, TypeListBuckets, "", "", start, nil)
 			}
 		}
-		
-	case TypeListBuckets:
-		input := &s3.ListBucketsInput{}
-		
-		result, err := b.client.ListBuckets(ctx, input)
+
+	case TypeHeadObject:
+		input := &s3.HeadObjectInput{
+			Bucket: &firstRequest.BucketName,
+			Key:    &firstRequest.Key,
+		}
+
+		b.metrics.CoalescedRequests.WithLabelValues(TypeHeadObject).Add(float64(len(requests)))
+		b.metrics.CoalescedCalls.WithLabelValues(TypeHeadObject).Inc()
+		start := time.Now()
+		result, err := b.store.HeadObject(ctx, input)
+		b.metrics.observeCall(TypeHeadObject, start)
 		if err != nil {
-			// Send error to all requests
+			b.metrics.recordErrors(TypeHeadObject, err, len(requests))
 			for _, req := range requests {
 				req.ErrorChan <- err
+				b.recordCompletion(events, TypeHeadObject, req.BucketName, req.Key, start, err)
 			}
 		} else {
-			// Send result to all requests
 			for _, req := range requests {
 				req.ResultChan <- result
+				b.recordCompletion(events, TypeHeadObject, req.BucketName, req.Key, start, nil)
 			}
 		}
-		
+
+	case TypePutObject:
+		input := &s3.PutObjectInput{
+			Bucket:      &firstRequest.BucketName,
+			Key:         &firstRequest.Key,
+			Body:        firstRequest.Body,
+			ContentType: awssdk.String(firstRequest.ContentType),
+		}
+
+		b.metrics.CoalescedRequests.WithLabelValues(TypePutObject).Add(1)
+		b.metrics.CoalescedCalls.WithLabelValues(TypePutObject).Inc()
+		start := time.Now()
+		result, err := b.store.PutObject(ctx, input)
+		b.metrics.observeCall(TypePutObject, start)
+		if err != nil {
+			b.metrics.recordErrors(TypePutObject, err, 1)
+			firstRequest.ErrorChan <- err
+		} else {
+			firstRequest.ResultChan <- result
+		}
+		b.recordCompletion(events, TypePutObject, firstRequest.BucketName, firstRequest.Key, start, err)
+
 	default:
 		err := fmt.Errorf("unsupported request type: %s", firstRequest.Type)
 		for _, req := range requests {
 			req.ErrorChan <- err
+			b.recordCompletion(events, firstRequest.Type, req.BucketName, req.Key, time.Now(), err)
+		}
+	}
+}
+
+// executeDeleteObjectsGroup deletes every key in requests (all the same
+// bucket) via S3's bulk DeleteObjects, chunked to respect
+// s3MaxDeleteObjectKeys, then routes each request's own per-key result (or
+// error) back by matching the response's Deleted/Errors slices against the
+// keys it submitted. events collects one sinks.Event per request completed
+// here, for the caller to forward via emitEvents once the whole batch is
+// done.
+func (b *AWSBatcher) executeDeleteObjectsGroup(ctx context.Context, requests []*BatchRequest, events *[]sinks.Event) {
+	bucket := requests[0].BucketName
+
+	for _, chunk := range chunkByCount(requests, s3MaxDeleteObjectKeys) {
+		byKey := make(map[string][]*BatchRequest, len(chunk))
+		objects := make([]types.ObjectIdentifier, len(chunk))
+		for i, req := range chunk {
+			objects[i] = types.ObjectIdentifier{Key: &req.Key}
+			byKey[req.Key] = append(byKey[req.Key], req)
+		}
+
+		slog.Info("batching: executing DeleteObjects", "bucket", bucket, "group_size", len(chunk), "request_ids", requestIDs(chunk))
+
+		b.metrics.CoalescedRequests.WithLabelValues(TypeDeleteObject).Add(float64(len(chunk)))
+		b.metrics.CoalescedCalls.WithLabelValues(TypeDeleteObject).Inc()
+		start := time.Now()
+		result, err := b.store.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: &bucket,
+			Delete: &types.Delete{Objects: objects},
+		})
+		b.metrics.observeCall(TypeDeleteObject, start)
+		if err != nil {
+			b.metrics.recordErrors(TypeDeleteObject, err, len(chunk))
+			for _, req := range chunk {
+				req.ErrorChan <- err
+				b.recordCompletion(events, TypeDeleteObject, bucket, req.Key, start, err)
+			}
+			continue
+		}
+
+		for _, deleted := range result.Deleted {
+			for _, req := range byKey[awssdk.ToString(deleted.Key)] {
+				req.ResultChan <- deleted
+				b.recordCompletion(events, TypeDeleteObject, bucket, req.Key, start, nil)
+			}
+			delete(byKey, awssdk.ToString(deleted.Key))
+		}
+		for _, objErr := range result.Errors {
+			b.metrics.Errors.WithLabelValues(TypeDeleteObject, awssdk.ToString(objErr.Code)).Inc()
+			err := fmt.Errorf("s3 DeleteObjects key %s failed: %s (%s)",
+				awssdk.ToString(objErr.Key), awssdk.ToString(objErr.Message), awssdk.ToString(objErr.Code))
+			for _, req := range byKey[awssdk.ToString(objErr.Key)] {
+				req.ErrorChan <- err
+				b.recordCompletion(events, TypeDeleteObject, bucket, req.Key, start, err)
+			}
+			delete(byKey, awssdk.ToString(objErr.Key))
+		}
+	}
+}
+
+// executeGetObjectGroup fetches a group of GetObject requests that all
+// target the same bucket:key with a single S3 call covering the union of
+// their requested byte ranges (or the full object if any request omits a
+// range), then slices the buffered body back out to each request's
+// ResultChan as its own io.ReadCloser view. events collects one sinks.Event
+// per request completed here, for the caller to forward via emitEvents once
+// the whole batch is done.
+func (b *AWSBatcher) executeGetObjectGroup(ctx context.Context, requests []*BatchRequest, events *[]sinks.Event) {
+	firstRequest := requests[0]
+	input := &s3.GetObjectInput{
+		Bucket: &firstRequest.BucketName,
+		Key:    &firstRequest.Key,
+	}
+	if firstRequest.IfNoneMatch != "" {
+		input.IfNoneMatch = &firstRequest.IfNoneMatch
+	}
+
+	fetchFullObject := false
+	var rangeStart, rangeEnd int64
+	haveRange := false
+	for _, req := range requests {
+		if req.RangeStart == nil || req.RangeEnd == nil {
+			fetchFullObject = true
+			continue
+		}
+		if !haveRange || *req.RangeStart < rangeStart {
+			rangeStart = *req.RangeStart
+		}
+		if !haveRange || *req.RangeEnd > rangeEnd {
+			rangeEnd = *req.RangeEnd
+		}
+		haveRange = true
+	}
+	if !fetchFullObject && haveRange {
+		rangeHeader := fmt.Sprintf("bytes=%d-%d", rangeStart, rangeEnd)
+		input.Range = &rangeHeader
+	}
+
+	slog.Info("batching: executing coalesced GetObject", "bucket", firstRequest.BucketName, "key", firstRequest.Key,
+		"group_size", len(requests), "range", awssdk.ToString(input.Range), "request_ids", requestIDs(requests))
+
+	b.metrics.CoalescedRequests.WithLabelValues(TypeGetObject).Add(float64(len(requests)))
+	b.metrics.CoalescedCalls.WithLabelValues(TypeGetObject).Inc()
+	start := time.Now()
+	result, err := b.store.GetObject(ctx, input)
+	b.metrics.observeCall(TypeGetObject, start)
+	if err != nil {
+		b.metrics.recordErrors(TypeGetObject, err, len(requests))
+		for _, req := range requests {
+			req.ErrorChan <- err
+			b.recordCompletion(events, TypeGetObject, req.BucketName, req.Key, start, err)
+		}
+		return
+	}
+	defer result.Body.Close()
+
+	body, err := newBufferedBody(result.Body, b.maxBufferBytes, len(requests))
+	if err != nil {
+		for _, req := range requests {
+			req.ErrorChan <- err
+			b.recordCompletion(events, TypeGetObject, req.BucketName, req.Key, start, err)
+		}
+		return
+	}
+
+	// totalSize is the full object's size, for building each request's own
+	// Content-Range header below. When the union fetch above covered only
+	// part of the object, that's the total S3 reported in its ContentRange
+	// response (the buffered body itself is only the union range); when it
+	// fetched the whole object, the buffered body's size already is the
+	// total.
+	totalSize := body.size
+	if result.ContentRange != nil {
+		if total, ok := parseContentRangeTotal(*result.ContentRange); ok {
+			totalSize = total
+		}
+	}
+
+	for _, req := range requests {
+		offset, length := int64(0), body.size
+		if req.RangeStart != nil && req.RangeEnd != nil {
+			// The buffered body starts at offset 0 of whatever S3 actually
+			// returned: the full object if fetchFullObject forced an
+			// unranged GetObject (e.g. another request in this group
+			// wanted the whole object), or the union range otherwise. Only
+			// in the latter case does a ranged request's own start need to
+			// be rebased against the union's start.
+			if fetchFullObject {
+				offset = *req.RangeStart
+			} else {
+				offset = *req.RangeStart - rangeStart
+			}
+			length = *req.RangeEnd - *req.RangeStart + 1
+		}
+
+		reader, err := body.reader(offset, length)
+		if err != nil {
+			req.ErrorChan <- err
+			b.recordCompletion(events, TypeGetObject, req.BucketName, req.Key, start, err)
+			continue
+		}
+
+		resp := *result
+		resp.Body = reader
+		resp.ContentLength = &length
+		if req.RangeStart != nil && req.RangeEnd != nil {
+			contentRange := fmt.Sprintf("bytes %d-%d/%d", *req.RangeStart, *req.RangeEnd, totalSize)
+			resp.ContentRange = &contentRange
+		} else {
+			resp.ContentRange = nil
+		}
+		req.ResultChan <- &resp
+		b.recordCompletion(events, TypeGetObject, req.BucketName, req.Key, start, nil)
+	}
+}
+
+// parseContentRangeTotal extracts the total object size from an S3
+// Content-Range response header ("bytes start-end/total"), for requests
+// whose own requested range is narrower than the union range actually
+// fetched from S3.
+func parseContentRangeTotal(contentRange string) (int64, bool) {
+	slash := strings.LastIndex(contentRange, "/")
+	if slash == -1 {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return total, true
+}
+
+// processSQSBatch fans a batch of SendMessage requests into one or more
+// SendMessageBatch calls, chunked to respect sqsMaxBatchMessages/
+// sqsMaxBatchBytes.
+func (b *AWSBatcher) processSQSBatch(ctx context.Context, batch []*BatchRequest) {
+	for _, chunk := range chunkByCountAndBytes(batch, sqsMaxBatchMessages, sqsMaxBatchBytes, func(req *BatchRequest) int {
+		return len(req.MessageBody)
+	}) {
+		b.executeSendMessageBatch(ctx, chunk)
+	}
+}
+
+// executeSendMessageBatch issues a single SendMessageBatch call for chunk
+// and demultiplexes the per-entry results back to each request's
+// ResultChan/ErrorChan by entry id.
+func (b *AWSBatcher) executeSendMessageBatch(ctx context.Context, chunk []*BatchRequest) {
+	if len(chunk) == 0 {
+		return
+	}
+
+	queueURL := chunk[0].QueueURL
+	entries := make([]sqstypes.SendMessageBatchRequestEntry, len(chunk))
+	byID := make(map[string]*BatchRequest, len(chunk))
+	for i, req := range chunk {
+		id := strconv.Itoa(i)
+		body := req.MessageBody
+		entries[i] = sqstypes.SendMessageBatchRequestEntry{
+			Id:          &id,
+			MessageBody: &body,
+		}
+		byID[id] = req
+	}
+
+	slog.Info("batching: executing SendMessageBatch", "queue_url", queueURL, "group_size", len(chunk), "request_ids", requestIDs(chunk))
+
+	b.metrics.CoalescedRequests.WithLabelValues(TypeSendMessage).Add(float64(len(chunk)))
+	b.metrics.CoalescedCalls.WithLabelValues(TypeSendMessage).Inc()
+	start := time.Now()
+	result, err := b.sqsClient.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: &queueURL,
+		Entries:  entries,
+	})
+	b.metrics.observeCall(TypeSendMessage, start)
+	if err != nil {
+		b.metrics.recordErrors(TypeSendMessage, err, len(chunk))
+		for _, req := range chunk {
+			req.ErrorChan <- err
+			b.recordCompletion(nil, TypeSendMessage, "", queueURL, start, err)
+		}
+		return
+	}
+
+	for _, success := range result.Successful {
+		if req, ok := byID[awssdk.ToString(success.Id)]; ok {
+			req.ResultChan <- success
+			b.recordCompletion(nil, TypeSendMessage, "", queueURL, start, nil)
+		}
+	}
+	for _, failure := range result.Failed {
+		req, ok := byID[awssdk.ToString(failure.Id)]
+		if !ok {
+			continue
+		}
+		b.metrics.Errors.WithLabelValues(TypeSendMessage, awssdk.ToString(failure.Code)).Inc()
+		err := fmt.Errorf("sqs SendMessageBatch entry %s failed: %s (%s)",
+			awssdk.ToString(failure.Id), awssdk.ToString(failure.Message), awssdk.ToString(failure.Code))
+		req.ErrorChan <- err
+		b.recordCompletion(nil, TypeSendMessage, "", queueURL, start, err)
+	}
+}
+
+// processKinesisBatch fans a batch of PutRecord requests into one or more
+// PutRecords calls, chunked to respect kinesisMaxBatchRecords/
+// kinesisMaxBatchBytes.
+func (b *AWSBatcher) processKinesisBatch(ctx context.Context, batch []*BatchRequest) {
+	for _, chunk := range chunkByCountAndBytes(batch, kinesisMaxBatchRecords, kinesisMaxBatchBytes, func(req *BatchRequest) int {
+		return len(req.Data)
+	}) {
+		b.executePutRecords(ctx, chunk)
+	}
+}
+
+// executePutRecords issues a single PutRecords call for chunk and
+// distributes the per-record results back to each request's ResultChan/
+// ErrorChan; unlike SendMessageBatch, PutRecords preserves request order in
+// its response so results are matched back up by index.
+func (b *AWSBatcher) executePutRecords(ctx context.Context, chunk []*BatchRequest) {
+	if len(chunk) == 0 {
+		return
+	}
+
+	streamName := chunk[0].StreamName
+	entries := make([]kinesistypes.PutRecordsRequestEntry, len(chunk))
+	for i, req := range chunk {
+		partitionKey := req.PartitionKey
+		entries[i] = kinesistypes.PutRecordsRequestEntry{
+			Data:         req.Data,
+			PartitionKey: &partitionKey,
+		}
+	}
+
+	slog.Info("batching: executing PutRecords", "stream_name", streamName, "group_size", len(chunk), "request_ids", requestIDs(chunk))
+
+	b.metrics.CoalescedRequests.WithLabelValues(TypePutRecord).Add(float64(len(chunk)))
+	b.metrics.CoalescedCalls.WithLabelValues(TypePutRecord).Inc()
+	start := time.Now()
+	result, err := b.kinesisClient.PutRecords(ctx, &kinesis.PutRecordsInput{
+		StreamName: &streamName,
+		Records:    entries,
+	})
+	b.metrics.observeCall(TypePutRecord, start)
+	if err != nil {
+		b.metrics.recordErrors(TypePutRecord, err, len(chunk))
+		for _, req := range chunk {
+			req.ErrorChan <- err
+			b.recordCompletion(nil, TypePutRecord, "", streamName, start, err)
+		}
+		return
+	}
+
+	for i, record := range result.Records {
+		req := chunk[i]
+		if record.ErrorCode != nil {
+			b.metrics.Errors.WithLabelValues(TypePutRecord, awssdk.ToString(record.ErrorCode)).Inc()
+			err := fmt.Errorf("kinesis PutRecords entry %d failed: %s (%s)",
+				i, awssdk.ToString(record.ErrorMessage), awssdk.ToString(record.ErrorCode))
+			req.ErrorChan <- err
+			b.recordCompletion(nil, TypePutRecord, "", streamName, start, err)
+			continue
 		}
+		req.ResultChan <- record
+		b.recordCompletion(nil, TypePutRecord, "", streamName, start, nil)
 	}
-} 
\ No newline at end of file
+}
+
+// requestIDs collects the RequestID of each request in a group, for slog
+// correlation; empty ids (requests that didn't set one) are omitted.
+func requestIDs(requests []*BatchRequest) []string {
+	ids := make([]string, 0, len(requests))
+	for _, req := range requests {
+		if req.RequestID != "" {
+			ids = append(ids, req.RequestID)
+		}
+	}
+	return ids
+}
+
+// chunkByCountAndBytes splits requests into groups of at most maxCount
+// entries whose sizeOf-summed bytes stay within maxBytes, preserving order.
+// A single request larger than maxBytes still gets its own chunk (it's
+// sent alone rather than dropped).
+func chunkByCountAndBytes(requests []*BatchRequest, maxCount, maxBytes int, sizeOf func(*BatchRequest) int) [][]*BatchRequest {
+	var chunks [][]*BatchRequest
+	var current []*BatchRequest
+	currentBytes := 0
+
+	for _, req := range requests {
+		size := sizeOf(req)
+		if len(current) > 0 && (len(current) >= maxCount || currentBytes+size > maxBytes) {
+			chunks = append(chunks, current)
+			current = nil
+			currentBytes = 0
+		}
+		current = append(current, req)
+		currentBytes += size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// chunkByCount splits requests into groups of at most maxCount entries,
+// preserving order; used where the backend's only limit is a count (S3's
+// DeleteObjects, up to 1000 keys) rather than count-and-bytes.
+func chunkByCount(requests []*BatchRequest, maxCount int) [][]*BatchRequest {
+	return chunkByCountAndBytes(requests, maxCount, int(^uint(0)>>1), func(*BatchRequest) int { return 0 })
+}
+
+// bufferedBody holds a GetObject body that's been read once so it can be
+// sliced and handed out to multiple callers as independent io.ReadClosers.
+// Bodies up to maxBufferBytes are kept in memory; larger ones spill to a
+// temp file so N concurrent callers don't each need their own full copy.
+type bufferedBody struct {
+	data []byte // non-nil when the body fit within maxBufferBytes
+	file *os.File
+	size int64
+	refs int32 // outstanding file-backed readers; the temp file is removed when this hits 0
+}
+
+// newBufferedBody reads r fully, buffering in memory up to maxBufferBytes
+// and spilling the rest to a temp file. expectedReaders is the number of
+// readers that will be requested via reader(), used to refcount the temp
+// file's lifetime.
+func newBufferedBody(r io.Reader, maxBufferBytes int64, expectedReaders int) (*bufferedBody, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBufferBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to buffer object body: %w", err)
+	}
+	if int64(len(data)) <= maxBufferBytes {
+		return &bufferedBody{data: data, size: int64(len(data))}, nil
+	}
+
+	f, err := os.CreateTemp("", "s3batch-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file for object body: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to write temp file for object body: %w", err)
+	}
+	rest, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to write temp file for object body: %w", err)
+	}
+
+	return &bufferedBody{file: f, size: int64(len(data)) + rest, refs: int32(expectedReaders)}, nil
+}
+
+// reader returns an io.ReadCloser over [offset, offset+length) of the
+// buffered body, relative to the start of the fetched range.
+func (b *bufferedBody) reader(offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 || length < 0 || offset+length > b.size {
+		return nil, fmt.Errorf("requested range [%d, %d) is out of bounds for a %d-byte body", offset, offset+length, b.size)
+	}
+	if b.data != nil {
+		return io.NopCloser(bytes.NewReader(b.data[offset : offset+length])), nil
+	}
+	return &fileBodyReader{SectionReader: io.NewSectionReader(b.file, offset, length), body: b}, nil
+}
+
+// fileBodyReader is the io.ReadCloser handed out for a file-backed
+// bufferedBody; closing it decrements the body's refcount and removes the
+// temp file once every reader has closed.
+type fileBodyReader struct {
+	*io.SectionReader
+	body *bufferedBody
+}
+
+func (f *fileBodyReader) Close() error {
+	if atomic.AddInt32(&f.body.refs, -1) == 0 {
+		f.body.file.Close()
+		os.Remove(f.body.file.Name())
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package batching
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerState is a circuit breaker for a single BatchRequest.Type: it trips
+// open after Threshold consecutive failures and stays open for Cooldown,
+// after which it lets one trial request through (half-open) to test whether
+// the backend has recovered.
+type breakerState struct {
+	mu sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+
+	consecutiveFailures int
+	open                bool
+	openedAt            time.Time
+
+	// trialInFlight marks that a half-open probe has been admitted and
+	// hasn't resolved yet (via RecordSuccess/RecordFailure), so Allow
+	// admits only one request at a time once cooldown elapses instead of
+	// every concurrent caller, which could re-overwhelm a backend that's
+	// still recovering.
+	trialInFlight bool
+}
+
+func newBreakerState(threshold int, cooldown time.Duration) *breakerState {
+	return &breakerState{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a request should be submitted: true unless the
+// breaker is open and either its cooldown hasn't elapsed yet or a half-open
+// probe admitted by a previous call is still in flight.
+func (b *breakerState) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return true
+	}
+	if b.trialInFlight || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trialInFlight = true
+	return true
+}
+
+// RecordSuccess closes the breaker, resets its failure count, and clears any
+// in-flight probe.
+func (b *breakerState) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures = 0
+	b.open = false
+	b.trialInFlight = false
+}
+
+// RecordFailure counts a failure, tripping the breaker open (or re-opening
+// it, restarting its cooldown, if this was a failed half-open probe) once
+// consecutive failures reach threshold.
+func (b *breakerState) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFailures++
+	b.trialInFlight = false
+	if b.consecutiveFailures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
+
+// BreakerStatus is a point-in-time view of one type's breaker state, for
+// exposing through an endpoint like /debug/breakers.
+type BreakerStatus struct {
+	Open                bool `json:"open"`
+	ConsecutiveFailures int  `json:"consecutive_failures"`
+}
+
+func (b *breakerState) Snapshot() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BreakerStatus{Open: b.open, ConsecutiveFailures: b.consecutiveFailures}
+}
+
+// CircuitBreaker tracks a breakerState per BatchRequest.Type, lazily
+// creating one the first time a type is seen, so callers can wrap
+// AWSBatcher.Submit without pre-registering every request type.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+// NewCircuitBreaker creates a CircuitBreaker that trips a request type's
+// breaker after threshold consecutive failures and holds it open for
+// cooldown before allowing a trial request through.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		states:    make(map[string]*breakerState),
+	}
+}
+
+func (c *CircuitBreaker) stateFor(requestType string) *breakerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state, ok := c.states[requestType]
+	if !ok {
+		state = newBreakerState(c.threshold, c.cooldown)
+		c.states[requestType] = state
+	}
+	return state
+}
+
+// Allow reports whether a request of requestType should be submitted.
+func (c *CircuitBreaker) Allow(requestType string) bool {
+	return c.stateFor(requestType).Allow()
+}
+
+// RecordResult updates requestType's breaker state after an attempt: a nil
+// err closes the breaker, a non-nil err counts toward tripping it open.
+func (c *CircuitBreaker) RecordResult(requestType string, err error) {
+	state := c.stateFor(requestType)
+	if err != nil {
+		state.RecordFailure()
+	} else {
+		state.RecordSuccess()
+	}
+}
+
+// Snapshot returns the current breaker state for every request type seen so
+// far, keyed by type, for /debug/breakers.
+func (c *CircuitBreaker) Snapshot() map[string]BreakerStatus {
+	c.mu.Lock()
+	types := make([]string, 0, len(c.states))
+	for t := range c.states {
+		types = append(types, t)
+	}
+	c.mu.Unlock()
+
+	snapshot := make(map[string]BreakerStatus, len(types))
+	for _, t := range types {
+		snapshot[t] = c.stateFor(t).Snapshot()
+	}
+	return snapshot
+}
@@ -0,0 +1,122 @@
+package batching
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors for an AWSBatcher's queues.
+// AWSBatcher gets its own Metrics registered to its own registry, rather
+// than a shared global one, so running more than one batcher in the same
+// process doesn't panic on duplicate metric registration.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	QueueDepth        *prometheus.GaugeVec
+	Window            *prometheus.GaugeVec
+	BatchSize         *prometheus.HistogramVec
+	BatchFillRatio    *prometheus.HistogramVec
+	BatchCloseReason  *prometheus.CounterVec
+	CallLatency       *prometheus.HistogramVec
+	CoalescedRequests *prometheus.CounterVec
+	CoalescedCalls    *prometheus.CounterVec
+	Errors            *prometheus.CounterVec
+	RequestsTotal     *prometheus.CounterVec
+	BatchWait         *prometheus.HistogramVec
+	Inflight          *prometheus.GaugeVec
+}
+
+// NewMetrics creates and registers a fresh set of collectors.
+func NewMetrics() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		registry: registry,
+		QueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "batching_queue_depth",
+			Help: "Number of requests currently waiting in a batching queue.",
+		}, []string{"queue"}),
+		Window: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "batching_window_seconds",
+			Help: "Batch window used for the current/most recent batch. Fixed for non-adaptive queues; EWMA-derived for adaptive ones.",
+		}, []string{"queue"}),
+		BatchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "batching_batch_size",
+			Help:    "Number of requests collected into each batch before it was processed.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"queue"}),
+		BatchFillRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "batching_batch_fill_ratio",
+			Help:    "Batch size divided by the queue's configured max batch size.",
+			Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+		}, []string{"queue"}),
+		BatchCloseReason: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batching_batch_close_total",
+			Help: "Batches processed, by whether the batch window closed because it filled up (size) or because the timer fired (timer).",
+		}, []string{"queue", "reason"}),
+		CallLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "batching_call_duration_seconds",
+			Help:    "Duration of each underlying call to the backend (S3/SQS/Kinesis), by operation.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		CoalescedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batching_coalesced_requests_total",
+			Help: "Incoming BatchRequests folded into grouped/batched calls, by operation.",
+		}, []string{"operation"}),
+		CoalescedCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batching_coalesced_calls_total",
+			Help: "Actual backend calls issued after coalescing, by operation. CoalescedRequests/CoalescedCalls is the coalescing win.",
+		}, []string{"operation"}),
+		Errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batching_errors_total",
+			Help: "Errors delivered to requests, by operation and backend error code.",
+		}, []string{"operation", "code"}),
+		RequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batching_requests_total",
+			Help: "Every BatchRequest delivered a result, by operation and outcome (success/error).",
+		}, []string{"operation", "status"}),
+		BatchWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "batching_batch_wait_seconds",
+			Help:    "Time a request spent queued before its batch was dispatched for processing.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"queue"}),
+		Inflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "batching_inflight",
+			Help: "Requests submitted but not yet delivered a result, by operation.",
+		}, []string{"operation"}),
+	}
+
+	registry.MustRegister(
+		m.QueueDepth, m.Window, m.BatchSize, m.BatchFillRatio, m.BatchCloseReason,
+		m.CallLatency, m.CoalescedRequests, m.CoalescedCalls, m.Errors,
+		m.RequestsTotal, m.BatchWait, m.Inflight,
+	)
+	return m
+}
+
+// Handler returns an http.Handler serving these metrics in the Prometheus
+// exposition format, suitable for mounting at e.g. /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) observeCall(operation string, start time.Time) {
+	m.CallLatency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// recordErrors tags err with an AWS error code (falling back to "unknown"
+// for errors that don't implement smithy.APIError) and counts it once per
+// request that received it.
+func (m *Metrics) recordErrors(operation string, err error, count int) {
+	code := "unknown"
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+	m.Errors.WithLabelValues(operation, code).Add(float64(count))
+}
@@ -0,0 +1,66 @@
+package batching
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// RetryPolicy configures exponential-backoff-with-jitter retries for
+// submissions that fail with a transient AWS error (see IsRetryable).
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+}
+
+// retryableCodes are the smithy API error codes worth retrying: throttling
+// and transient server-side failures. Anything else (bad input, auth,
+// not-found) fails immediately since retrying it can't change the outcome.
+var retryableCodes = map[string]bool{
+	"SlowDown":            true,
+	"RequestTimeout":      true,
+	"InternalError":       true,
+	"ServiceUnavailable":  true,
+	"Throttling":          true,
+	"ThrottlingException": true,
+}
+
+// IsRetryable reports whether err is a transient AWS API error (by code)
+// worth retrying.
+func IsRetryable(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return retryableCodes[apiErr.ErrorCode()]
+	}
+	return false
+}
+
+// IsNotModified reports whether err is a 304 Not Modified response to a
+// GetObject request with IfNoneMatch set - from S3 (smithyhttp.ResponseError)
+// or Azure (azcore.ResponseError, returned by azureStore.GetObject's native
+// IfNoneMatch precondition). Unlike IsRetryable's codes, this isn't a
+// transient failure worth retrying: it's the condition evaluating true, and
+// the caller (see cmd/agent's handleGetObject) should surface it as a 304
+// rather than an error.
+func IsNotModified(err error) bool {
+	var smithyErr *smithyhttp.ResponseError
+	if errors.As(err, &smithyErr) && smithyErr.HTTPStatusCode() == 304 {
+		return true
+	}
+	var azureErr *azcore.ResponseError
+	return errors.As(err, &azureErr) && azureErr.StatusCode == 304
+}
+
+// Backoff returns the delay before retry attempt n (0-indexed, the attempt
+// about to be made, not the one that just failed): BaseDelay*2^n plus up to
+// BaseDelay of jitter, so concurrent callers retrying the same failure
+// don't all retry in lockstep.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.BaseDelay << attempt
+	jitter := time.Duration(rand.Int63n(int64(p.BaseDelay) + 1))
+	return backoff + jitter
+}
@@ -0,0 +1,105 @@
+package batching
+
+import (
+	"context"
+	"fmt"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectStore is the object-storage surface AWSBatcher's S3 queue batches
+// against. Every backend (AWS S3, GCS, Azure Blob, or an S3-compatible
+// endpoint like MinIO/R2) implements it in terms of the same s3 SDK
+// input/output types, so the batching/coalescing logic in
+// processS3Batch/executeGetObjectGroup and the HTTP handlers in cmd/agent
+// stay identical regardless of which backend is configured. *s3.Client
+// satisfies this directly except for ListObjects, which wraps ListObjectsV2.
+type ObjectStore interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	ListObjects(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+
+	// PutObject uploads params.Body, using a multipart upload transparently
+	// for bodies large enough to benefit from one (see s3Store.PutObject).
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+
+	// DeleteObjects deletes up to 1000 keys from one bucket in a single call;
+	// executeDeleteObjectsGroup is what actually groups many single-key
+	// BatchRequests into one of these.
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+
+	// HeadObject returns an object's metadata without its body.
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+}
+
+// s3Store adapts *s3.Client to ObjectStore, forwarding ListObjects to the v2
+// API (the only method name that doesn't already match the SDK client).
+type s3Store struct {
+	client   *s3.Client
+	uploader *manager.Uploader
+}
+
+// NewS3Store builds an ObjectStore backed directly by AWS S3.
+func NewS3Store(client *s3.Client) ObjectStore {
+	return &s3Store{client: client, uploader: manager.NewUploader(client)}
+}
+
+func (s *s3Store) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return s.client.GetObject(ctx, params, optFns...)
+}
+
+func (s *s3Store) ListObjects(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return s.client.ListObjectsV2(ctx, params, optFns...)
+}
+
+func (s *s3Store) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	return s.client.ListBuckets(ctx, params, optFns...)
+}
+
+// PutObject uploads through manager.Uploader, which transparently switches
+// to a multipart upload (parallel UploadPart calls) once the body crosses
+// its part-size threshold instead of a single PutObject call.
+func (s *s3Store) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	result, err := s.uploader.Upload(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("s3 upload: %w", err)
+	}
+	return &s3.PutObjectOutput{
+		ETag:       result.ETag,
+		Expiration: result.Expiration,
+		VersionId:  result.VersionID,
+	}, nil
+}
+
+func (s *s3Store) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return s.client.DeleteObjects(ctx, params, optFns...)
+}
+
+func (s *s3Store) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return s.client.HeadObject(ctx, params, optFns...)
+}
+
+// NewMinIOStore builds an ObjectStore against any S3-compatible endpoint
+// (MinIO, Cloudflare R2, ...) by pointing an *s3.Client at a custom base
+// endpoint instead of AWS's regional endpoints, with path-style addressing
+// since most S3-compatible servers don't support virtual-hosted buckets.
+func NewMinIOStore(cfg awssdk.Config, endpoint string, usePathStyle bool) ObjectStore {
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = awssdk.String(endpoint)
+		o.UsePathStyle = usePathStyle
+	})
+	return &s3Store{client: client, uploader: manager.NewUploader(client)}
+}
+
+// parseByteRange parses an HTTP Range header value of the form
+// "bytes=start-end" (the format executeGetObjectGroup builds) into its
+// inclusive start/end offsets, for backends whose native SDK takes an
+// offset/length pair instead of a Range header.
+func parseByteRange(rangeHeader string) (start, end int64, err error) {
+	if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+		return 0, 0, fmt.Errorf("invalid range header %q: %w", rangeHeader, err)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,171 @@
+package batching
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// azureStore adapts an Azure Blob Storage client to ObjectStore, translating
+// Azure's native types to the same s3 SDK output structs the S3 backend
+// returns. "Bucket" maps to an Azure container.
+type azureStore struct {
+	client *azblob.Client
+}
+
+// NewAzureStore builds an ObjectStore backed by Azure Blob Storage.
+func NewAzureStore(client *azblob.Client) ObjectStore {
+	return &azureStore{client: client}
+}
+
+func (a *azureStore) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	opts := &azblob.DownloadStreamOptions{}
+	if r := params.Range; r != nil {
+		start, end, err := parseByteRange(*r)
+		if err != nil {
+			return nil, err
+		}
+		opts.Range = blob.HTTPRange{Offset: start, Count: end - start + 1}
+	}
+	if params.IfNoneMatch != nil {
+		etag := azcore.ETag(*params.IfNoneMatch)
+		opts.AccessConditions = &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &etag},
+		}
+	}
+
+	resp, err := a.client.DownloadStream(ctx, awssdk.ToString(params.Bucket), awssdk.ToString(params.Key), opts)
+	if err != nil {
+		return nil, fmt.Errorf("azure DownloadStream: %w", err)
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          resp.Body,
+		ContentLength: resp.ContentLength,
+	}, nil
+}
+
+func (a *azureStore) ListObjects(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	maxKeys := int32(1000)
+	if params.MaxKeys != nil && *params.MaxKeys > 0 {
+		maxKeys = *params.MaxKeys
+	}
+
+	pager := a.client.NewListBlobsFlatPager(awssdk.ToString(params.Bucket), &container.ListBlobsFlatOptions{
+		Prefix: params.Prefix,
+	})
+
+	var contents []types.Object
+	for pager.More() && int32(len(contents)) < maxKeys {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("azure list blobs: %w", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if int32(len(contents)) >= maxKeys {
+				break
+			}
+			var size int64
+			if item.Properties != nil && item.Properties.ContentLength != nil {
+				size = *item.Properties.ContentLength
+			}
+			contents = append(contents, types.Object{
+				Key:          item.Name,
+				Size:         &size,
+				LastModified: item.Properties.LastModified,
+			})
+		}
+	}
+
+	return &s3.ListObjectsV2Output{
+		Contents: contents,
+		KeyCount: awssdk.Int32(int32(len(contents))),
+		Name:     params.Bucket,
+		Prefix:   params.Prefix,
+	}, nil
+}
+
+// PutObject uploads params.Body via UploadStream, which (like GCS's Writer)
+// already chunks large bodies internally.
+func (a *azureStore) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	resp, err := a.client.UploadStream(ctx, awssdk.ToString(params.Bucket), awssdk.ToString(params.Key), params.Body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("azure UploadStream: %w", err)
+	}
+
+	var etag *string
+	if resp.ETag != nil {
+		etagStr := string(*resp.ETag)
+		etag = &etagStr
+	}
+	return &s3.PutObjectOutput{ETag: etag}, nil
+}
+
+// DeleteObjects deletes each blob individually: Azure Blob Storage has no
+// bulk-delete API equivalent to S3's DeleteObjects, so
+// executeDeleteObjectsGroup's coalescing only saves HTTP round trips for the
+// S3 backend.
+func (a *azureStore) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	bucket := awssdk.ToString(params.Bucket)
+
+	var deleted []types.DeletedObject
+	var errs []types.Error
+	for _, obj := range params.Delete.Objects {
+		key := awssdk.ToString(obj.Key)
+		if _, err := a.client.DeleteBlob(ctx, bucket, key, nil); err != nil {
+			message := err.Error()
+			errs = append(errs, types.Error{Key: obj.Key, Message: &message})
+			continue
+		}
+		deleted = append(deleted, types.DeletedObject{Key: obj.Key})
+	}
+
+	return &s3.DeleteObjectsOutput{Deleted: deleted, Errors: errs}, nil
+}
+
+func (a *azureStore) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	blobClient := a.client.ServiceClient().NewContainerClient(awssdk.ToString(params.Bucket)).NewBlobClient(awssdk.ToString(params.Key))
+	props, err := blobClient.GetProperties(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("azure GetProperties: %w", err)
+	}
+
+	var etag *string
+	if props.ETag != nil {
+		etagStr := string(*props.ETag)
+		etag = &etagStr
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: props.ContentLength,
+		ETag:          etag,
+		LastModified:  props.LastModified,
+	}, nil
+}
+
+func (a *azureStore) ListBuckets(ctx context.Context, params *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+	pager := a.client.NewListContainersPager(nil)
+
+	var buckets []types.Bucket
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("azure list containers: %w", err)
+		}
+		for _, c := range page.ContainerItems {
+			buckets = append(buckets, types.Bucket{
+				Name:         c.Name,
+				CreationDate: c.Properties.LastModified,
+			})
+		}
+	}
+
+	return &s3.ListBucketsOutput{Buckets: buckets}, nil
+}
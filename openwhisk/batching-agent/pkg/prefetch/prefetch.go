@@ -0,0 +1,372 @@
+// Package prefetch pulls a configured set of objects onto local disk ahead
+// of time, KFServing-multi-model-puller style: a JSON config file lists
+// {name, bucket, key} entries, and a Manager keeps a local directory in
+// sync with it, downloading added/changed entries through the agent's
+// existing batching.AWSBatcher and deleting the local file for anything
+// removed from the config.
+package prefetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/batching"
+)
+
+// Entry is one model/object the Manager keeps pulled, as listed in the
+// prefetch config file.
+type Entry struct {
+	Name   string `json:"name"`
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// State is where an Entry stands in the pull lifecycle.
+type State string
+
+const (
+	StatePending State = "pending"
+	StateReady   State = "ready"
+	StateFailed  State = "failed"
+)
+
+// Status is a point-in-time view of one Entry, for /prefetch/status.
+type Status struct {
+	State     State  `json:"state"`
+	SHA256    string `json:"sha256,omitempty"`
+	Size      int64  `json:"size,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Manager watches a JSON config file of Entries and keeps Dir's contents in
+// sync with it: added/changed entries are (re)downloaded through Batcher,
+// removed entries have their local file deleted. Create one with
+// NewManager, then call Start.
+type Manager struct {
+	configPath  string
+	dir         string
+	batcher     *batching.AWSBatcher
+	workers     int
+	retryPolicy batching.RetryPolicy
+
+	mu      sync.Mutex
+	status  map[string]Status
+	current map[string]Entry
+
+	jobs   chan Entry
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewManager creates a Manager. Objects are downloaded via batcher (the
+// same AWSBatcher the agent's HTTP handlers use) into dir, with workers
+// concurrent pulls in flight and retryPolicy governing per-object retry.
+func NewManager(configPath, dir string, batcher *batching.AWSBatcher, workers int, retryPolicy batching.RetryPolicy) *Manager {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Manager{
+		configPath:  configPath,
+		dir:         dir,
+		batcher:     batcher,
+		workers:     workers,
+		retryPolicy: retryPolicy,
+		status:      make(map[string]Status),
+		current:     make(map[string]Entry),
+	}
+}
+
+// Start creates dir if needed, launches the worker pool, reconciles once
+// against the config file immediately, and then watches for further
+// changes: fsnotify events on configPath and SIGHUP. It returns once the
+// watcher is running; reconciliation and pulls continue in the background
+// until ctx is cancelled or Stop is called.
+func (m *Manager) Start(ctx context.Context) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create prefetch dir %s: %w", m.dir, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create prefetch config watcher: %w", err)
+	}
+	// Watch the containing directory rather than the file itself: editors
+	// commonly replace a config file (write-and-rename) rather than
+	// overwrite it in place, which a watch on the file alone would miss.
+	if err := watcher.Add(filepath.Dir(m.configPath)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(m.configPath), err)
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	m.jobs = make(chan Entry, 64)
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.worker(ctx)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	m.reconcile()
+
+	m.wg.Add(1)
+	go m.watch(ctx, watcher, sighup)
+
+	return nil
+}
+
+// Stop cancels in-flight pulls and the watcher, and waits for everything to
+// exit.
+func (m *Manager) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+}
+
+// Status returns a snapshot of every entry's current Status, keyed by name.
+func (m *Manager) Status() map[string]Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]Status, len(m.status))
+	for name, status := range m.status {
+		snapshot[name] = status
+	}
+	return snapshot
+}
+
+// watch reconciles on every relevant fsnotify event or SIGHUP, until ctx is
+// cancelled.
+func (m *Manager) watch(ctx context.Context, watcher *fsnotify.Watcher, sighup chan os.Signal) {
+	defer m.wg.Done()
+	defer watcher.Close()
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(m.configPath) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				m.reconcile()
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Error("prefetch: config watcher error", "error", err)
+
+		case <-sighup:
+			slog.Info("prefetch: SIGHUP received, reconciling")
+			m.reconcile()
+		}
+	}
+}
+
+// reconcile loads the config file and diffs it against the last-reconciled
+// state: new or changed entries are queued for download, entries no longer
+// present have their local file removed.
+func (m *Manager) reconcile() {
+	entries, err := loadConfig(m.configPath)
+	if err != nil {
+		slog.Error("prefetch: failed to load config", "path", m.configPath, "error", err)
+		return
+	}
+
+	next := make(map[string]Entry, len(entries))
+	for _, entry := range entries {
+		next[entry.Name] = entry
+	}
+
+	m.mu.Lock()
+	var removed []string
+	for name := range m.current {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	var toPull []Entry
+	for name, entry := range next {
+		if prev, ok := m.current[name]; !ok || prev != entry {
+			toPull = append(toPull, entry)
+			m.status[name] = Status{State: StatePending}
+		}
+	}
+	m.current = next
+	m.mu.Unlock()
+
+	for _, name := range removed {
+		m.remove(name)
+	}
+	for _, entry := range toPull {
+		m.jobs <- entry
+	}
+}
+
+// remove deletes an entry's local file and status after it's dropped from
+// the config.
+func (m *Manager) remove(name string) {
+	m.mu.Lock()
+	delete(m.status, name)
+	m.mu.Unlock()
+
+	path := filepath.Join(m.dir, name)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		slog.Error("prefetch: failed to remove local file", "name", name, "path", path, "error", err)
+		return
+	}
+	slog.Info("prefetch: removed model", "name", name, "path", path)
+}
+
+// loadConfig reads and parses the prefetch config file: a JSON array of
+// Entries.
+func loadConfig(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid prefetch config: %w", err)
+	}
+	return entries, nil
+}
+
+// worker pulls entries off m.jobs until ctx is cancelled.
+func (m *Manager) worker(ctx context.Context) {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-m.jobs:
+			if !ok {
+				return
+			}
+			m.pull(ctx, entry)
+		}
+	}
+}
+
+// pull downloads entry, retrying per m.retryPolicy on failure, and records
+// the final Status (ready or failed).
+func (m *Manager) pull(ctx context.Context, entry Entry) {
+	var lastErr error
+	for attempt := 0; attempt <= m.retryPolicy.MaxRetries; attempt++ {
+		if ctx.Err() != nil {
+			return
+		}
+		if attempt > 0 {
+			select {
+			case <-time.After(m.retryPolicy.Backoff(attempt - 1)):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		status, err := m.download(ctx, entry)
+		if err == nil {
+			m.mu.Lock()
+			m.status[entry.Name] = status
+			m.mu.Unlock()
+			slog.Info("prefetch: pulled model", "name", entry.Name, "bucket", entry.Bucket, "key", entry.Key, "size", status.Size)
+			return
+		}
+
+		lastErr = err
+		slog.Warn("prefetch: pull attempt failed", "name", entry.Name, "attempt", attempt+1, "error", err)
+	}
+
+	m.mu.Lock()
+	m.status[entry.Name] = Status{State: StateFailed, LastError: lastErr.Error()}
+	m.mu.Unlock()
+}
+
+// download fetches entry through m.batcher (the same AWSBatcher the agent's
+// HTTP handlers submit GetObject requests to) and writes the body to disk.
+func (m *Manager) download(ctx context.Context, entry Entry) (Status, error) {
+	resultChan := make(chan any, 1)
+	errorChan := make(chan error, 1)
+
+	m.batcher.Submit(ctx, &batching.BatchRequest{
+		Type:       batching.TypeGetObject,
+		RequestID:  fmt.Sprintf("prefetch-%s", entry.Name),
+		BucketName: entry.Bucket,
+		Key:        entry.Key,
+		ResultChan: resultChan,
+		ErrorChan:  errorChan,
+	})
+
+	select {
+	case result := <-resultChan:
+		resp, ok := result.(*s3.GetObjectOutput)
+		if !ok {
+			return Status{}, fmt.Errorf("unexpected response type for GetObject")
+		}
+		defer resp.Body.Close()
+		return m.writeToDisk(entry.Name, resp.Body)
+
+	case err := <-errorChan:
+		return Status{}, err
+
+	case <-ctx.Done():
+		return Status{}, ctx.Err()
+	}
+}
+
+// writeToDisk streams body to dir/name, computing its sha256 along the way,
+// via a temp file renamed into place so a concurrent reader of the model
+// file never observes a partial write.
+func (m *Manager) writeToDisk(name string, body io.Reader) (Status, error) {
+	path := filepath.Join(m.dir, name)
+	tmpPath := path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(f, io.TeeReader(body, hasher))
+	if err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return Status{}, fmt.Errorf("failed to write model to disk: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return Status{}, fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return Status{}, fmt.Errorf("failed to finalize model file: %w", err)
+	}
+
+	return Status{State: StateReady, SHA256: hex.EncodeToString(hasher.Sum(nil)), Size: size}, nil
+}
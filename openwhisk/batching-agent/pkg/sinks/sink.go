@@ -0,0 +1,57 @@
+// Package sinks defines the pluggable destination AWSBatcher forwards
+// completed-request telemetry to: one structured Event per BatchRequest,
+// emitted whenever a batch finishes processing. A sink package (e.g.
+// pkg/sinks/sqs) registers itself here via Register in an init() function,
+// so adding a new destination (Kinesis, an HTTP webhook) doesn't require
+// changing AWSBatcher or cmd/agent.
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Event is a structured record of one BatchRequest's outcome: what kind of
+// request it was, what it targeted, how long the underlying call took, and
+// whether it failed.
+type Event struct {
+	Type    string        `json:"type"`
+	Bucket  string        `json:"bucket,omitempty"`
+	Key     string        `json:"key,omitempty"`
+	Latency time.Duration `json:"latency_ns"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Sink receives batches of Events, e.g. to forward them to SQS, Kinesis, or
+// an HTTP webhook. Emit is fire-and-forget from the caller's perspective: a
+// sink that can't deliver should log and drop rather than block the
+// batching pipeline.
+type Sink interface {
+	Emit(ctx context.Context, events []Event)
+	Close() error
+}
+
+// Builder constructs a Sink from its configuration string (typically a
+// queue URL, stream name, or endpoint). Sink packages register one under
+// their name via Register.
+type Builder func(config string) (Sink, error)
+
+var builders = map[string]Builder{}
+
+// Register adds a named sink Builder. Sink packages call this from an
+// init() function so importing the package (even blank-imported) makes the
+// sink available to Build.
+func Register(name string, builder Builder) {
+	builders[name] = builder
+}
+
+// Build constructs the named sink via its registered Builder. name must
+// match a name a sink package registered itself under (e.g. "sqs").
+func Build(name, config string) (Sink, error) {
+	builder, ok := builders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown event sink %q", name)
+	}
+	return builder(config)
+}
@@ -0,0 +1,94 @@
+// Package sqssink forwards sinks.Events to an SQS queue via
+// SendMessageBatch, registering itself with pkg/sinks under the name "sqs".
+package sqssink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/sinks"
+)
+
+func init() {
+	sinks.Register("sqs", Configure)
+}
+
+// maxBatchMessages is SendMessageBatch's own per-call limit.
+const maxBatchMessages = 10
+
+// Sink emits events to a single SQS queue.
+type Sink struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+// Configure builds a Sink against queueURL, loading AWS credentials the same
+// way the rest of the agent does (the default config chain).
+func Configure(queueURL string) (sinks.Sink, error) {
+	if queueURL == "" {
+		return nil, fmt.Errorf("sqs event sink requires a queue URL")
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	return &Sink{client: sqs.NewFromConfig(cfg), queueURL: queueURL}, nil
+}
+
+// Emit JSON-encodes each event and sends them to the queue via
+// SendMessageBatch, chunked to maxBatchMessages per call. A chunk that fails
+// to send is logged and dropped: event delivery is best-effort and must
+// never block the batching pipeline it's reporting on.
+func (s *Sink) Emit(ctx context.Context, events []sinks.Event) {
+	for start := 0; start < len(events); start += maxBatchMessages {
+		end := start + maxBatchMessages
+		if end > len(events) {
+			end = len(events)
+		}
+		s.sendChunk(ctx, events[start:end])
+	}
+}
+
+func (s *Sink) sendChunk(ctx context.Context, chunk []sinks.Event) {
+	entries := make([]sqstypes.SendMessageBatchRequestEntry, len(chunk))
+	for i, event := range chunk {
+		body, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("sqs event sink: failed to marshal event", "error", err)
+			return
+		}
+		id := strconv.Itoa(i)
+		entries[i] = sqstypes.SendMessageBatchRequestEntry{
+			Id:          &id,
+			MessageBody: awssdk.String(string(body)),
+		}
+	}
+
+	result, err := s.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: &s.queueURL,
+		Entries:  entries,
+	})
+	if err != nil {
+		slog.Error("sqs event sink: SendMessageBatch failed", "queue_url", s.queueURL, "count", len(chunk), "error", err)
+		return
+	}
+	for _, failure := range result.Failed {
+		slog.Error("sqs event sink: entry failed", "queue_url", s.queueURL,
+			"code", awssdk.ToString(failure.Code), "message", awssdk.ToString(failure.Message))
+	}
+}
+
+// Close is a no-op: the underlying sqs.Client needs no explicit shutdown.
+func (s *Sink) Close() error {
+	return nil
+}
@@ -0,0 +1,46 @@
+// Package tracing configures OpenTelemetry distributed tracing for the
+// batching agent: one span per incoming HTTP request (via otelhttp at the
+// router), one span per batch dispatch linking back to every request
+// folded into it (see batching.AWSBatcher), and one span per underlying AWS
+// SDK call (via otelaws middleware).
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Setup points the global TracerProvider at an OTLP/gRPC collector running
+// at endpoint. endpoint == "" disables tracing: otel.Tracer calls then
+// return the package's default no-op tracer, so callers don't need to
+// branch on whether tracing is enabled. The returned shutdown flushes
+// pending spans and should be called once during agent shutdown.
+func Setup(ctx context.Context, endpoint, serviceName string) (shutdown func(context.Context) error, err error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(endpoint), otlptracegrpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenTelemetry resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
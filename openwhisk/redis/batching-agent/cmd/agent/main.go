@@ -1,244 +1,95 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	"github.com/redis/go-redis/v9"
+	"github.com/serverless-benchmarks/redis-batching-agent/pkg/batching"
+	"github.com/serverless-benchmarks/redis-batching-agent/pkg/stats"
 	"github.com/valyala/fasthttp"
-	"golang.org/x/net/context"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
 )
 
 // Configuration for the agent
 type Configuration struct {
-	Port           int
-	BatchWindow    time.Duration
-	MaxBatchSize   int
-	RedisHost      string
-	RedisPort      string
-	RedisPassword  string
-	RedisPoolSize  int
-}
-
-// Operation types
-type OpType string
-const (
-	TypeGet OpType = "get"
-	TypeSet OpType = "set"
-	TypeDel OpType = "del"
-)
-
-// Request represents a Redis operation request
-type Request struct {
-	Type     OpType
-	Key      string
-	Value    string
-	ResultCh chan Result
-}
-
-// Result represents the result of a Redis operation
-type Result struct {
-	Value string
-	Error error
-}
-
-// Batcher handles batching Redis operations
-type Batcher struct {
-	client       *redis.Client
-	requests     chan *Request
-	batchWindow  time.Duration
-	maxBatchSize int
-	wg           sync.WaitGroup
-	shutdown     chan struct{}
-}
-
-// NewBatcher creates a new Redis batcher
-func NewBatcher(config Configuration) (*Batcher, error) {
-	// Create Redis client
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort),
-		Password: config.RedisPassword,
-		PoolSize: config.RedisPoolSize,
-	})
-
-	// Verify connection
-	ctx := context.Background()
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("Redis connection failed: %v", err)
-	}
-
-	log.Printf("Redis connection successful to %s:%s", config.RedisHost, config.RedisPort)
-
-	batcher := &Batcher{
-		client:       client,
-		requests:     make(chan *Request, config.MaxBatchSize*10), // Buffer to handle spikes
-		batchWindow:  config.BatchWindow,
-		maxBatchSize: config.MaxBatchSize,
-		shutdown:     make(chan struct{}),
-	}
-
-	// Start the processing goroutine
-	batcher.wg.Add(1)
-	go batcher.processRequests()
-
-	return batcher, nil
-}
-
-// Submit adds a request to the batching queue
-func (b *Batcher) Submit(req *Request) {
-	select {
-	case b.requests <- req:
-		// Request successfully added to queue
-	case <-b.shutdown:
-		// Batcher is shutting down
-		req.ResultCh <- Result{Error: fmt.Errorf("batcher is shutting down")}
-	}
-}
-
-// Shutdown stops the batcher gracefully
-func (b *Batcher) Shutdown() error {
-	close(b.shutdown)
-	b.wg.Wait()
-	return b.client.Close()
-}
-
-// processRequests processes batches of requests
-func (b *Batcher) processRequests() {
-	defer b.wg.Done()
-
-	for {
-		select {
-		case <-b.shutdown:
-			return
-		default:
-			b.processBatch()
-		}
-	}
-}
-
-// processBatch collects and executes a batch of requests
-func (b *Batcher) processBatch() {
-	ctx := context.Background()
-	batch := make([]*Request, 0, b.maxBatchSize)
-	timer := time.NewTimer(b.batchWindow)
-
-	// Wait for first request or exit if shutdown is signaled
-	select {
-	case req := <-b.requests:
-		batch = append(batch, req)
-		timer.Reset(b.batchWindow)
-	case <-b.shutdown:
-		timer.Stop()
-		return
-	}
-
-	// Collect requests until batch is full or window expires
-collectLoop:
-	for len(batch) < b.maxBatchSize {
-		select {
-		case req := <-b.requests:
-			batch = append(batch, req)
-		case <-timer.C:
-			break collectLoop
-		case <-b.shutdown:
-			timer.Stop()
-			for _, req := range batch {
-				req.ResultCh <- Result{Error: fmt.Errorf("batcher is shutting down")}
-			}
-			return
-		}
-	}
-
-	timer.Stop()
-
-	// Process the batch with pipelining
-	if len(batch) > 0 {
-		log.Printf("Processing batch of %d requests", len(batch))
-		
-		// Create a pipeline
-		pipe := b.client.Pipeline()
-		
-		// Group requests by type for tracking
-		getRequests := make(map[int]*Request)
-		setRequests := make(map[int]*Request)
-		delRequests := make(map[int]*Request)
-		
-		// Add commands to pipeline
-		for i, req := range batch {
-			switch req.Type {
-			case TypeGet:
-				getRequests[i] = req
-				pipe.Get(ctx, req.Key)
-			case TypeSet:
-				setRequests[i] = req
-				pipe.Set(ctx, req.Key, req.Value, 0)
-			case TypeDel:
-				delRequests[i] = req
-				pipe.Del(ctx, req.Key)
-			}
-		}
-		
-		// Execute pipeline
-		results, err := pipe.Exec(ctx)
-		
-		// If there was a global error, return it to all requesters
-		if err != nil && err != redis.Nil {
-			log.Printf("Pipeline execution error: %v", err)
-			for _, req := range batch {
-				req.ResultCh <- Result{Error: err}
-			}
-			return
-		}
-		
-		// Process results
-		for i, result := range results {
-			switch {
-			case i < len(getRequests):
-				req := getRequests[i]
-				if result.Err() != nil && result.Err() != redis.Nil {
-					req.ResultCh <- Result{Error: result.Err()}
-				} else {
-					value, _ := result.(*redis.StringCmd).Result()
-					req.ResultCh <- Result{Value: value}
-				}
-			case i < len(getRequests) + len(setRequests):
-				req := setRequests[i-len(getRequests)]
-				if result.Err() != nil {
-					req.ResultCh <- Result{Error: result.Err()}
-				} else {
-					req.ResultCh <- Result{Value: "OK"}
-				}
-			case i < len(getRequests) + len(setRequests) + len(delRequests):
-				req := delRequests[i-len(getRequests)-len(setRequests)]
-				if result.Err() != nil {
-					req.ResultCh <- Result{Error: result.Err()}
-				} else {
-					count, _ := result.(*redis.IntCmd).Result()
-					req.ResultCh <- Result{Value: strconv.FormatInt(count, 10)}
-				}
-			}
-		}
-	}
+	Port          int
+	BatchWindow   time.Duration
+	MaxBatchSize  int
+	RedisHost     string
+	RedisPort     string
+	RedisPassword string
+	RedisPoolSize int
+
+	// RedisMode selects the topology NewBatcher connects to: "standalone"
+	// (default, a single node at RedisHost:RedisPort), "sentinel", or
+	// "cluster". RedisAddrs lists every node/sentinel to address in
+	// sentinel/cluster mode; SentinelMasterName is required in sentinel mode.
+	RedisMode          string
+	RedisAddrs         []string
+	SentinelMasterName string
+
+	// BackendURI, if set, selects the storage backend by connection URI via
+	// batching.NewBatcherFromURIWithPolicy (e.g. "leveldb:///path/to/db" to
+	// run Redis-free, or any of the redis://, rediss://, redis-sentinel://,
+	// redis-cluster:// schemes) instead of RedisMode/RedisHost/RedisAddrs.
+	BackendURI string
+
+	// CacheBytes and CacheTTL size the read-through GET cache in front of the
+	// batcher; see newCache.
+	CacheBytes int64
+	CacheTTL   time.Duration
+
+	// AdaptiveBatching selects batching.AdaptiveWindow over a fixed
+	// BatchWindow: the window shrinks towards MinBatchWindow under load (to
+	// bound tail latency) and grows back towards MaxBatchWindow when traffic
+	// is sparse (to maximize pipelining), using BatchWindow as the fill-time
+	// target it adapts around.
+	AdaptiveBatching bool
+	MinBatchWindow   time.Duration
+	MaxBatchWindow   time.Duration
+
+	// RequestTimeout bounds how long submitAndWait waits for a request's
+	// result before giving up: *fasthttp.RequestCtx doesn't implement real
+	// per-request deadlines or disconnect propagation (Deadline always
+	// returns ok=false; Done/Err only fire on server-wide shutdown), so this
+	// is the only thing standing between a stuck Backend call and a batch
+	// pinned forever (see submitAndWait).
+	RequestTimeout time.Duration
 }
 
 // Server handles HTTP requests
 type Server struct {
-	batcher *Batcher
-	server  *fasthttp.Server
+	batcher        *batching.RedisBatcher
+	cache          *batching.Cache
+	latency        *stats.Recorder
+	metrics        http.Handler
+	server         *fasthttp.Server
+	requestTimeout time.Duration
 }
 
-// NewServer creates a new HTTP server
-func NewServer(batcher *Batcher, port int) *Server {
+// NewServer creates a new HTTP server. If batcher.Invalidations() returns a
+// channel (only when it's backed by Redis), NewServer spawns a goroutine that
+// evicts cache entries as invalidations arrive, so the cache stays coherent
+// with writes from this agent and any other instance sharing the same Redis
+// invalidation channel.
+func NewServer(batcher *batching.RedisBatcher, cache *batching.Cache, port int, requestTimeout time.Duration) *Server {
 	server := &Server{
-		batcher: batcher,
+		batcher:        batcher,
+		cache:          cache,
+		latency:        stats.NewRecorder(),
+		metrics:        batcher.Metrics().Handler(),
+		requestTimeout: requestTimeout,
 	}
 
 	// Create fasthttp server
@@ -247,6 +98,14 @@ func NewServer(batcher *Batcher, port int) *Server {
 		Name:    "Redis Batching Agent",
 	}
 
+	if invalidations := batcher.Invalidations(); invalidations != nil {
+		go func() {
+			for key := range invalidations {
+				cache.Invalidate(key)
+			}
+		}()
+	}
+
 	return server
 }
 
@@ -269,12 +128,38 @@ func (s *Server) handleRequest(ctx *fasthttp.RequestCtx) {
 	switch {
 	case path == "/health" && method == "GET":
 		s.handleHealth(ctx)
+	case path == "/stats" && method == "GET":
+		s.handleStats(ctx)
+	case path == "/metrics" && method == "GET":
+		s.handleMetrics(ctx)
 	case path == "/redis/get" && method == "GET":
 		s.handleGet(ctx)
 	case path == "/redis/set" && method == "POST":
 		s.handleSet(ctx)
 	case path == "/redis/del" && method == "DELETE":
 		s.handleDel(ctx)
+	case path == "/redis/exists" && method == "GET":
+		s.handleExists(ctx)
+	case path == "/redis/mget" && method == "POST":
+		s.handleMGet(ctx)
+	case path == "/redis/mset" && method == "POST":
+		s.handleMSet(ctx)
+	case path == "/redis/setex" && method == "POST":
+		s.handleSetEx(ctx)
+	case path == "/redis/expire" && method == "POST":
+		s.handleExpire(ctx)
+	case path == "/redis/incr" && method == "POST":
+		s.handleIncr(ctx)
+	case path == "/redis/decr" && method == "POST":
+		s.handleDecr(ctx)
+	case path == "/redis/incrby" && method == "POST":
+		s.handleIncrBy(ctx)
+	case path == "/redis/hget" && method == "GET":
+		s.handleHGet(ctx)
+	case path == "/redis/hset" && method == "POST":
+		s.handleHSet(ctx)
+	case path == "/redis/hdel" && method == "DELETE":
+		s.handleHDel(ctx)
 	default:
 		ctx.Error("Not Found", fasthttp.StatusNotFound)
 	}
@@ -286,7 +171,61 @@ func (s *Server) handleHealth(ctx *fasthttp.RequestCtx) {
 	ctx.SetBodyString("OK")
 }
 
-// handleGet handles GET requests
+// submitAndWait submits request to the batcher and blocks for its result,
+// returning the delivered value or error. request.Ctx is set to a fresh
+// context.WithTimeout(s.requestTimeout), not ctx itself: *fasthttp.RequestCtx
+// implements the context.Context interface but not its contract - its
+// Deadline always reports ok=false and its Done/Err only fire on server-wide
+// shutdown, not per-request cancellation or client disconnect - so it can
+// never actually bound processBatch's Backend call (see expireStaleRequests).
+// The real timeout context can. The full submit-to-delivery latency is
+// recorded regardless of outcome, so /metrics and /stats reflect what a
+// client actually waited, not just successful ops.
+func (s *Server) submitAndWait(ctx *fasthttp.RequestCtx, request *batching.BatchRequest) (any, error) {
+	start := time.Now()
+	resultChan := make(chan any, 1)
+	errorChan := make(chan error, 1)
+	request.ResultChan = resultChan
+	request.ErrorChan = errorChan
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
+	defer cancel()
+	request.Ctx = reqCtx
+
+	s.batcher.Submit(ctx, request)
+
+	select {
+	case value := <-resultChan:
+		s.latency.Record(time.Since(start))
+		return value, nil
+	case err := <-errorChan:
+		s.latency.Record(time.Since(start))
+		return nil, err
+	case <-reqCtx.Done():
+		s.latency.Record(time.Since(start))
+		return nil, reqCtx.Err()
+	}
+}
+
+// cachedGet returns key's value from the read-through cache on a hit, or
+// submits a GET to the batcher and populates the cache on a miss.
+func (s *Server) cachedGet(ctx *fasthttp.RequestCtx, key string) (string, error) {
+	if value, ok := s.cache.Get(key); ok {
+		return value, nil
+	}
+
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeGet, Key: key})
+	if err != nil {
+		return "", err
+	}
+
+	value, _ := result.(string)
+	s.cache.Set(key, value)
+	return value, nil
+}
+
+// handleGet handles GET requests, consulting the read-through cache before
+// falling back to the batcher on a miss.
 func (s *Server) handleGet(ctx *fasthttp.RequestCtx) {
 	key := string(ctx.QueryArgs().Peek("key"))
 	if key == "" {
@@ -294,22 +233,43 @@ func (s *Server) handleGet(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	resultCh := make(chan Result, 1)
-	req := &Request{
-		Type:     TypeGet,
-		Key:      key,
-		ResultCh: resultCh,
+	value, err := s.cachedGet(ctx, key)
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to get value: %v", err), fasthttp.StatusInternalServerError)
+		return
 	}
 
-	s.batcher.Submit(req)
-	result := <-resultCh
-
-	if result.Error != nil {
-		ctx.Error(fmt.Sprintf("Failed to get value: %v", result.Error), fasthttp.StatusInternalServerError)
+	response := map[string]string{"value": value}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]string{"value": result.Value}
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// handleStats reports cache/dedupe effectiveness counters, the batcher's
+// current effective flush window, and request latency percentiles, for
+// load-testing visibility into adaptive batching from the invoker tool.
+func (s *Server) handleStats(ctx *fasthttp.RequestCtx) {
+	cacheHits, cacheMisses := s.cache.Stats()
+	dedupeTotal, dedupeHits := s.batcher.DedupeStats()
+	latency := s.latency.Snapshot()
+
+	response := map[string]int64{
+		"cache_hits":          cacheHits,
+		"cache_misses":        cacheMisses,
+		"dedupe_total":        dedupeTotal,
+		"dedupe_hits":         dedupeHits,
+		"current_window_usec": s.batcher.CurrentWindow().Microseconds(),
+		"request_count":       latency.Count,
+		"request_p50_usec":    latency.P50.Microseconds(),
+		"request_p90_usec":    latency.P90.Microseconds(),
+		"request_p99_usec":    latency.P99.Microseconds(),
+		"request_p999_usec":   latency.P999.Microseconds(),
+	}
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
@@ -320,6 +280,14 @@ func (s *Server) handleGet(ctx *fasthttp.RequestCtx) {
 	ctx.SetBody(jsonResponse)
 }
 
+// handleMetrics serves the batcher's Prometheus collectors (queue depth,
+// batch size/fill time/exec duration histograms, op error counts, dedupe
+// hits) in the standard exposition format, adapted from the net/http
+// handler promhttp.HandlerFor returns since this agent is built on fasthttp.
+func (s *Server) handleMetrics(ctx *fasthttp.RequestCtx) {
+	fasthttpadaptor.NewFastHTTPHandler(s.metrics)(ctx)
+}
+
 // handleSet handles SET requests
 func (s *Server) handleSet(ctx *fasthttp.RequestCtx) {
 	key := string(ctx.QueryArgs().Peek("key"))
@@ -334,23 +302,13 @@ func (s *Server) handleSet(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	resultCh := make(chan Result, 1)
-	req := &Request{
-		Type:     TypeSet,
-		Key:      key,
-		Value:    value,
-		ResultCh: resultCh,
-	}
-
-	s.batcher.Submit(req)
-	result := <-resultCh
-
-	if result.Error != nil {
-		ctx.Error(fmt.Sprintf("Failed to set value: %v", result.Error), fasthttp.StatusInternalServerError)
+	_, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeSet, Key: key, Value: value})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to set value: %v", err), fasthttp.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]string{"result": result.Value}
+	response := map[string]string{"result": "OK"}
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
@@ -369,22 +327,39 @@ func (s *Server) handleDel(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	resultCh := make(chan Result, 1)
-	req := &Request{
-		Type:     TypeDel,
-		Key:      key,
-		ResultCh: resultCh,
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeDel, Key: key})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to delete key: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	deleted := fmt.Sprintf("%v", result)
+	response := map[string]string{"deleted": deleted}
+	jsonResponse, err := json.Marshal(response)
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
 	}
 
-	s.batcher.Submit(req)
-	result := <-resultCh
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
 
-	if result.Error != nil {
-		ctx.Error(fmt.Sprintf("Failed to delete key: %v", result.Error), fasthttp.StatusInternalServerError)
+// handleExists handles EXISTS requests
+func (s *Server) handleExists(ctx *fasthttp.RequestCtx) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	if key == "" {
+		ctx.Error("Missing required parameter: key", fasthttp.StatusBadRequest)
 		return
 	}
 
-	response := map[string]string{"deleted": result.Value}
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeExists, Key: key})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to check existence: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]string{"exists": fmt.Sprintf("%v", result)}
 	jsonResponse, err := json.Marshal(response)
 	if err != nil {
 		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
@@ -395,6 +370,279 @@ func (s *Server) handleDel(ctx *fasthttp.RequestCtx) {
 	ctx.SetBody(jsonResponse)
 }
 
+// handleMGet handles batched multi-get requests. The body is a JSON array of
+// keys; each key is submitted as its own BatchRequest so ExecuteBatch's
+// existing per-batch grouping-by-key coalesces them into one Lua call,
+// rather than this handler doing its own coalescing.
+func (s *Server) handleMGet(ctx *fasthttp.RequestCtx) {
+	var keys []string
+	if err := json.Unmarshal(ctx.PostBody(), &keys); err != nil {
+		ctx.Error(fmt.Sprintf("Invalid request body: %v", err), fasthttp.StatusBadRequest)
+		return
+	}
+
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			value, err := s.cachedGet(ctx, key)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			values[i] = value
+		}(i, key)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			ctx.Error(fmt.Sprintf("Failed to get values: %v", err), fasthttp.StatusInternalServerError)
+			return
+		}
+	}
+
+	jsonResponse, err := json.Marshal(map[string][]string{"values": values})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// handleMSet handles batched multi-set requests. The body is a JSON object
+// mapping key to value; each pair is submitted as its own BatchRequest so
+// ExecuteBatch's existing per-batch grouping coalesces them into one Lua call.
+func (s *Server) handleMSet(ctx *fasthttp.RequestCtx) {
+	var pairs map[string]string
+	if err := json.Unmarshal(ctx.PostBody(), &pairs); err != nil {
+		ctx.Error(fmt.Sprintf("Invalid request body: %v", err), fasthttp.StatusBadRequest)
+		return
+	}
+
+	errs := make([]error, 0, len(pairs))
+	var errsMu sync.Mutex
+	var wg sync.WaitGroup
+	for key, value := range pairs {
+		wg.Add(1)
+		go func(key, value string) {
+			defer wg.Done()
+			_, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeSet, Key: key, Value: value})
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+			}
+		}(key, value)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		ctx.Error(fmt.Sprintf("Failed to set values: %v", errs[0]), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse, err := json.Marshal(map[string]string{"result": "OK"})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// handleSetEx handles SETEX requests
+func (s *Server) handleSetEx(ctx *fasthttp.RequestCtx) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	value := string(ctx.QueryArgs().Peek("value"))
+	if key == "" || value == "" {
+		ctx.Error("Missing required parameter: key and value", fasthttp.StatusBadRequest)
+		return
+	}
+
+	ttlSeconds, err := ctx.QueryArgs().GetUint("ttl")
+	if err != nil || ttlSeconds <= 0 {
+		ctx.Error("Missing or invalid required parameter: ttl", fasthttp.StatusBadRequest)
+		return
+	}
+
+	_, err = s.submitAndWait(ctx, &batching.BatchRequest{
+		Type: batching.TypeSetEx, Key: key, Value: value, TTL: time.Duration(ttlSeconds) * time.Second,
+	})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to setex value: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	respondOK(ctx)
+}
+
+// handleExpire handles EXPIRE requests
+func (s *Server) handleExpire(ctx *fasthttp.RequestCtx) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	if key == "" {
+		ctx.Error("Missing required parameter: key", fasthttp.StatusBadRequest)
+		return
+	}
+
+	ttlSeconds, err := ctx.QueryArgs().GetUint("ttl")
+	if err != nil || ttlSeconds <= 0 {
+		ctx.Error("Missing or invalid required parameter: ttl", fasthttp.StatusBadRequest)
+		return
+	}
+
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{
+		Type: batching.TypeExpire, Key: key, TTL: time.Duration(ttlSeconds) * time.Second,
+	})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to expire key: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse, err := json.Marshal(map[string]string{"expired": fmt.Sprintf("%v", result)})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// handleIncr handles INCR requests
+func (s *Server) handleIncr(ctx *fasthttp.RequestCtx) {
+	s.handleIncrByDelta(ctx, batching.TypeIncr, 0)
+}
+
+// handleDecr handles DECR requests
+func (s *Server) handleDecr(ctx *fasthttp.RequestCtx) {
+	s.handleIncrByDelta(ctx, batching.TypeDecr, 0)
+}
+
+// handleIncrBy handles INCRBY requests
+func (s *Server) handleIncrBy(ctx *fasthttp.RequestCtx) {
+	amount, err := strconv.ParseInt(string(ctx.QueryArgs().Peek("amount")), 10, 64)
+	if err != nil {
+		ctx.Error("Missing or invalid required parameter: amount", fasthttp.StatusBadRequest)
+		return
+	}
+	s.handleIncrByDelta(ctx, batching.TypeIncrBy, amount)
+}
+
+// handleIncrByDelta is the shared implementation behind Incr/Decr/IncrBy: the
+// amount only matters for TypeIncrBy, since incrDelta resolves Incr/Decr to
+// +1/-1 itself.
+func (s *Server) handleIncrByDelta(ctx *fasthttp.RequestCtx, opType string, amount int64) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	if key == "" {
+		ctx.Error("Missing required parameter: key", fasthttp.StatusBadRequest)
+		return
+	}
+
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: opType, Key: key, Amount: amount})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to increment key: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse, err := json.Marshal(map[string]string{"value": fmt.Sprintf("%v", result)})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// handleHGet handles HGET requests
+func (s *Server) handleHGet(ctx *fasthttp.RequestCtx) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	field := string(ctx.QueryArgs().Peek("field"))
+	if key == "" || field == "" {
+		ctx.Error("Missing required parameter: key and field", fasthttp.StatusBadRequest)
+		return
+	}
+
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeHGet, Key: key, Field: field})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to hget value: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	value, _ := result.(string)
+	jsonResponse, err := json.Marshal(map[string]string{"value": value})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// handleHSet handles HSET requests
+func (s *Server) handleHSet(ctx *fasthttp.RequestCtx) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	field := string(ctx.QueryArgs().Peek("field"))
+	value := string(ctx.QueryArgs().Peek("value"))
+	if key == "" || field == "" || value == "" {
+		ctx.Error("Missing required parameter: key, field, and value", fasthttp.StatusBadRequest)
+		return
+	}
+
+	_, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeHSet, Key: key, Field: field, Value: value})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to hset value: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	respondOK(ctx)
+}
+
+// handleHDel handles HDEL requests
+func (s *Server) handleHDel(ctx *fasthttp.RequestCtx) {
+	key := string(ctx.QueryArgs().Peek("key"))
+	field := string(ctx.QueryArgs().Peek("field"))
+	if key == "" || field == "" {
+		ctx.Error("Missing required parameter: key and field", fasthttp.StatusBadRequest)
+		return
+	}
+
+	result, err := s.submitAndWait(ctx, &batching.BatchRequest{Type: batching.TypeHDel, Key: key, Field: field})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to hdel field: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	jsonResponse, err := json.Marshal(map[string]string{"deleted": fmt.Sprintf("%v", result)})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
+// respondOK writes the common {"result":"OK"} response body used by
+// fire-and-forget write handlers.
+func respondOK(ctx *fasthttp.RequestCtx) {
+	jsonResponse, err := json.Marshal(map[string]string{"result": "OK"})
+	if err != nil {
+		ctx.Error(fmt.Sprintf("Failed to marshal response: %v", err), fasthttp.StatusInternalServerError)
+		return
+	}
+	ctx.SetContentType("application/json")
+	ctx.SetBody(jsonResponse)
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
 		return value
@@ -402,15 +650,58 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// newBatcher builds the pkg/batching.RedisBatcher. If config.BackendURI is
+// set, it dispatches on URI scheme via batching.NewBatcherFromURIWithPolicy
+// (the only way to reach batching.NewLevelDBBatcher's embedded, Redis-free
+// backend from this agent). Otherwise it falls back to config.RedisMode:
+// "standalone" (default) addresses RedisHost:RedisPort directly; "sentinel"
+// and "cluster" address every host in RedisAddrs.
+func newBatcher(config Configuration) (*batching.RedisBatcher, error) {
+	var policy batching.FlushPolicy = batching.NewFixedWindow(config.BatchWindow)
+	if config.AdaptiveBatching {
+		policy = batching.NewAdaptiveWindow(config.MinBatchWindow, config.MaxBatchWindow, config.BatchWindow)
+	}
+
+	if config.BackendURI != "" {
+		return batching.NewBatcherFromURIWithPolicy(
+			config.BackendURI, config.RedisPoolSize, true, policy, config.MaxBatchSize,
+		)
+	}
+
+	addrs := config.RedisAddrs
+	if len(addrs) == 0 && (config.RedisMode == "" || config.RedisMode == "standalone") {
+		addrs = []string{fmt.Sprintf("%s:%s", config.RedisHost, config.RedisPort)}
+	}
+	return batching.NewRedisBatcherFromMode(
+		config.RedisMode, addrs, config.SentinelMasterName, config.RedisPassword,
+		config.RedisPoolSize, true, policy, config.MaxBatchSize,
+	)
+}
+
+// newCache builds the read-through GET cache sized by config.CacheBytes/CacheTTL.
+func newCache(config Configuration) *batching.Cache {
+	return batching.NewCache(config.CacheBytes, config.CacheTTL)
+}
+
 func main() {
 	// Parse command-line flags
 	portFlag := flag.Int("port", 8080, "HTTP server port")
-	redisHostFlag := flag.String("redis-host", "", "Redis host")
-	redisPortFlag := flag.String("redis-port", "6379", "Redis port")
+	redisHostFlag := flag.String("redis-host", "", "Redis host (standalone mode)")
+	redisPortFlag := flag.String("redis-port", "6379", "Redis port (standalone mode)")
 	redisPasswordFlag := flag.String("redis-password", "", "Redis password")
 	redisPoolSizeFlag := flag.Int("redis-pool-size", 10, "Redis connection pool size")
+	redisModeFlag := flag.String("redis-mode", "standalone", "Redis topology: standalone, sentinel, or cluster")
+	redisAddrsFlag := flag.String("redis-addrs", "", "Comma-separated host:port list (sentinel/cluster mode)")
+	sentinelMasterNameFlag := flag.String("sentinel-master-name", "", "Sentinel master name (sentinel mode)")
+	backendURIFlag := flag.String("backend-uri", "", "Connection URI selecting the storage backend by scheme (e.g. leveldb:///path/to/db, redis://host:port); overrides -redis-mode/-redis-host/-redis-addrs when set")
 	batchWindowFlag := flag.Duration("batch-window", 100*time.Millisecond, "Batch collection window")
 	maxBatchSizeFlag := flag.Int("max-batch-size", 10, "Maximum batch size")
+	cacheBytesFlag := flag.Int64("cache-bytes", 16*1024*1024, "Byte budget for the read-through GET cache")
+	cacheTTLFlag := flag.Duration("cache-ttl", 30*time.Second, "TTL for entries in the read-through GET cache")
+	adaptiveBatchingFlag := flag.Bool("adaptive-batching", false, "Adapt the batch window to observed load instead of using a fixed window")
+	minBatchWindowFlag := flag.Duration("min-batch-window", 10*time.Millisecond, "Minimum batch window in adaptive mode")
+	maxBatchWindowFlag := flag.Duration("max-batch-window", 500*time.Millisecond, "Maximum batch window in adaptive mode")
+	requestTimeoutFlag := flag.Duration("request-timeout", 5*time.Second, "Maximum time to wait for a request's result before giving up and bounding its batch's Backend call")
 	flag.Parse()
 
 	// Override with environment variables if set
@@ -419,7 +710,15 @@ func main() {
 	redisPort := getEnvOrDefault("REDIS_PORT", *redisPortFlag)
 	redisPassword := getEnvOrDefault("REDIS_PASSWORD", *redisPasswordFlag)
 	redisPoolSize, _ := strconv.Atoi(getEnvOrDefault("REDIS_POOL_SIZE", strconv.Itoa(*redisPoolSizeFlag)))
-	
+	redisMode := getEnvOrDefault("REDIS_MODE", *redisModeFlag)
+	sentinelMasterName := getEnvOrDefault("SENTINEL_MASTER_NAME", *sentinelMasterNameFlag)
+	backendURI := getEnvOrDefault("BACKEND_URI", *backendURIFlag)
+
+	var redisAddrs []string
+	if addrsStr := getEnvOrDefault("REDIS_ADDRS", *redisAddrsFlag); addrsStr != "" {
+		redisAddrs = strings.Split(addrsStr, ",")
+	}
+
 	batchWindowStr := getEnvOrDefault("BATCH_WINDOW", "")
 	batchWindow := *batchWindowFlag
 	if batchWindowStr != "" {
@@ -427,43 +726,76 @@ func main() {
 			batchWindow = parsedWindow
 		}
 	}
-	
+
 	maxBatchSize, _ := strconv.Atoi(getEnvOrDefault("MAX_BATCH_SIZE", strconv.Itoa(*maxBatchSizeFlag)))
 
-	// Validate Redis host
-	if redisHost == "" {
+	cacheBytes, _ := strconv.ParseInt(getEnvOrDefault("CACHE_BYTES", strconv.FormatInt(*cacheBytesFlag, 10)), 10, 64)
+	cacheTTL := *cacheTTLFlag
+	if cacheTTLStr := getEnvOrDefault("CACHE_TTL", ""); cacheTTLStr != "" {
+		if parsedTTL, err := time.ParseDuration(cacheTTLStr); err == nil {
+			cacheTTL = parsedTTL
+		}
+	}
+
+	// Validate Redis host, unless a backend URI was given to select the
+	// backend some other way (e.g. leveldb:///path/to/db needs none of this).
+	if backendURI == "" && redisMode == "standalone" && redisHost == "" && len(redisAddrs) == 0 {
 		log.Fatal("Redis host is required")
 	}
 
 	// Create configuration
 	config := Configuration{
-		Port:           port,
-		BatchWindow:    batchWindow,
-		MaxBatchSize:   maxBatchSize,
-		RedisHost:      redisHost,
-		RedisPort:      redisPort,
-		RedisPassword:  redisPassword,
-		RedisPoolSize:  redisPoolSize,
+		Port:               port,
+		BatchWindow:        batchWindow,
+		MaxBatchSize:       maxBatchSize,
+		RedisHost:          redisHost,
+		RedisPort:          redisPort,
+		RedisPassword:      redisPassword,
+		RedisPoolSize:      redisPoolSize,
+		RedisMode:          redisMode,
+		RedisAddrs:         redisAddrs,
+		SentinelMasterName: sentinelMasterName,
+		BackendURI:         backendURI,
+		CacheBytes:         cacheBytes,
+		CacheTTL:           cacheTTL,
+		AdaptiveBatching:   *adaptiveBatchingFlag,
+		MinBatchWindow:     *minBatchWindowFlag,
+		MaxBatchWindow:     *maxBatchWindowFlag,
+		RequestTimeout:     *requestTimeoutFlag,
 	}
 
 	// Print configuration
 	log.Printf("Starting Redis batching agent with configuration:")
 	log.Printf("  Port: %d", config.Port)
-	log.Printf("  Redis Host: %s", config.RedisHost)
-	log.Printf("  Redis Port: %s", config.RedisPort)
+	if config.BackendURI != "" {
+		log.Printf("  Backend URI: %s", config.BackendURI)
+	} else {
+		log.Printf("  Redis Mode: %s", config.RedisMode)
+		log.Printf("  Redis Host: %s", config.RedisHost)
+		log.Printf("  Redis Port: %s", config.RedisPort)
+		log.Printf("  Redis Addrs: %v", config.RedisAddrs)
+	}
 	log.Printf("  Batch Window: %v", config.BatchWindow)
 	log.Printf("  Max Batch Size: %d", config.MaxBatchSize)
 	log.Printf("  Redis Pool Size: %d", config.RedisPoolSize)
+	log.Printf("  Cache Bytes: %d", config.CacheBytes)
+	log.Printf("  Cache TTL: %v", config.CacheTTL)
+	log.Printf("  Adaptive Batching: %v", config.AdaptiveBatching)
+	if config.AdaptiveBatching {
+		log.Printf("  Min Batch Window: %v", config.MinBatchWindow)
+		log.Printf("  Max Batch Window: %v", config.MaxBatchWindow)
+	}
+	log.Printf("  Request Timeout: %v", config.RequestTimeout)
 
 	// Create batcher
-	batcher, err := NewBatcher(config)
+	batcher, err := newBatcher(config)
 	if err != nil {
 		log.Fatalf("Failed to create batcher: %v", err)
 	}
 	defer batcher.Shutdown()
 
 	// Create server
-	server := NewServer(batcher, config.Port)
+	server := NewServer(batcher, newCache(config), config.Port, config.RequestTimeout)
 
 	// Set up signal handling for graceful shutdown
 	signals := make(chan os.Signal, 1)
@@ -486,7 +818,5 @@ func main() {
 	}
 
 	// Shut down batcher
-	if err := batcher.Shutdown(); err != nil {
-		log.Printf("Error shutting down batcher: %v", err)
-	}
-} 
\ No newline at end of file
+	batcher.Shutdown()
+}
@@ -0,0 +1,35 @@
+package batching
+
+import "context"
+
+// Backend executes a batch of requests against a particular storage engine.
+// RedisBatcher is backend-agnostic: it only owns the queueing/windowing
+// mechanics and defers the actual GET/SET/DEL/EXISTS work to whichever
+// Backend it was constructed with.
+type Backend interface {
+	// ExecuteBatch runs every request in batch and delivers each result (or
+	// error) on its ResultChan/ErrorChan. It only returns an error for
+	// failures that prevent the batch from being attempted at all; per-request
+	// failures are reported through the individual channels.
+	ExecuteBatch(ctx context.Context, batch []*BatchRequest) error
+
+	// Close releases any resources (connections, file handles) held by the
+	// backend.
+	Close() error
+}
+
+// invalidationSource is implemented by Backends that can notify a read-through
+// Cache when a key's value has changed - only RedisBackend, via Redis pub/sub,
+// since LevelDBBackend has no remote subscribers to tell. Tested with a type
+// assertion the same way metricsConsumer and Hedger are, rather than growing
+// the Backend interface for a capability most Backends don't have.
+type invalidationSource interface {
+	Invalidations() <-chan string
+}
+
+// dedupeStatsSource is implemented by Backends that track in-flight
+// GET/EXISTS/HGET dedupe, the same optional-capability pattern as
+// invalidationSource.
+type dedupeStatsSource interface {
+	DedupeStats() (total, hits int64)
+}
@@ -2,65 +2,282 @@ package batching
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer emits spans covering a BatchRequest's life (Submit through result
+// delivery) and the batch that carried it.
+var tracer = otel.Tracer("github.com/serverless-benchmarks/redis-batching-agent/pkg/batching")
+
 // Operation types
 const (
 	TypeGet    = "Get"
 	TypeSet    = "Set"
 	TypeDel    = "Del"
 	TypeExists = "Exists"
+
+	// TypeSetEx and TypeExpire carry a TTL (BatchRequest.TTL).
+	TypeSetEx  = "SetEx"
+	TypeExpire = "Expire"
+
+	// TypeIncr/TypeDecr/TypeIncrBy all resolve to a Redis INCRBY under the
+	// hood (see incrDelta); TypeIncrBy reads BatchRequest.Amount as the
+	// delta, TypeIncr/TypeDecr use +1/-1.
+	TypeIncr   = "Incr"
+	TypeDecr   = "Decr"
+	TypeIncrBy = "IncrBy"
+
+	// TypeHGet/TypeHSet/TypeHDel operate on a hash field (BatchRequest.Field).
+	TypeHGet = "HGet"
+	TypeHSet = "HSet"
+	TypeHDel = "HDel"
 )
 
-// BatchRequest represents a generic Redis request that can be batched
+// BatchRequest represents a generic key/value request that can be batched
 type BatchRequest struct {
-	Type        string      // Type of request (Get, Set, Del, etc.)
-	Key         string      // Redis key
-	Value       string      // For Set requests
-	ResultChan  chan any    // Channel to deliver result
-	ErrorChan   chan error  // Channel to deliver errors
+	Type       string     // Type of request (Get, Set, Del, etc.)
+	Key        string     // Key
+	Value      string     // For Set/SetEx/HSet requests
+	ResultChan chan any   // Channel to deliver result
+	ErrorChan  chan error // Channel to deliver errors
+
+	// Ctx, if set, is the caller's per-request context - a real
+	// context.WithTimeout, not the *fasthttp.RequestCtx a handler receives
+	// (that type's Deadline/Done/Err don't track per-request state; see
+	// cmd/agent's submitAndWait). processBatch skips requests whose Ctx has
+	// already expired before handing the batch to the Backend, and derives
+	// the batch's execution deadline from the earliest Deadline among the
+	// requests it does execute. A nil Ctx never expires and never
+	// contributes a deadline.
+	Ctx context.Context
+
+	// TTL is the expiration for SetEx/Expire requests.
+	TTL time.Duration
+
+	// Amount is the delta for IncrBy requests; Incr/Decr always use +1/-1.
+	Amount int64
+
+	// Field is the hash field for HGet/HSet/HDel requests.
+	Field string
+
+	spanCtx trace.SpanContext // context of the span opened for this request in Submit, linked from the batch span in processBatch
 }
 
-// RedisBatcher handles batching Redis requests
+// RedisBatcher queues requests and flushes them, on a timer or once
+// maxBatchSize is reached, to a Backend. The name predates the Backend
+// abstraction: despite it, a RedisBatcher can just as well front a
+// LevelDBBackend - it only owns the queueing/windowing mechanics, not the
+// storage engine.
 type RedisBatcher struct {
-	client          *redis.Client
+	backend         Backend
 	enabled         bool
-	batchWindow     time.Duration
+	policy          FlushPolicy
 	maxBatchSize    int
 	pendingRequests chan *BatchRequest
-	mu              sync.Mutex
 	wg              sync.WaitGroup
+	metrics         *Metrics
 }
 
-// NewRedisBatcher creates a new Redis batcher
+// NewRedisBatcher creates a new batcher backed by a single Redis node,
+// flushing on a fixed batchWindow. Use NewRedisBatcherWithPolicy for
+// adaptive, byte-budgeted, or hedged flushing.
 func NewRedisBatcher(redisHost string, redisPort string, redisPassword string, poolSize int, enabled bool, batchWindow time.Duration, maxBatchSize int) *RedisBatcher {
-	// Create Redis client
+	return NewRedisBatcherWithPolicy(redisHost, redisPort, redisPassword, poolSize, enabled, NewFixedWindow(batchWindow), maxBatchSize)
+}
+
+// NewRedisBatcherWithPolicy creates a new batcher backed by a single Redis
+// node, using policy to decide when to flush a batch.
+func NewRedisBatcherWithPolicy(redisHost string, redisPort string, redisPassword string, poolSize int, enabled bool, policy FlushPolicy, maxBatchSize int) *RedisBatcher {
 	client := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
 		Password: redisPassword,
 		PoolSize: poolSize,
 	})
 
+	return newRedisBatcher(client, false, enabled, policy, maxBatchSize)
+}
+
+// NewRedisBatcherFromURL creates a new batcher from a connection URI,
+// dispatching on scheme to support single-node, Sentinel, and Cluster
+// topologies:
+//
+//	redis://host:port                  single node
+//	rediss://host:port                  single node over TLS
+//	redis-sentinel://host:port,host:port?master=mymaster
+//	redis-cluster://host:port,host:port
+//
+// It returns a redis.UniversalClient so callers don't need to distinguish
+// between the underlying *redis.Client, *redis.ClusterClient, or
+// *redis.FailoverClient.
+func NewRedisBatcherFromURL(uri string, poolSize int, enabled bool, batchWindow time.Duration, maxBatchSize int) (*RedisBatcher, error) {
+	return NewBatcherFromURIWithPolicy(uri, poolSize, enabled, NewFixedWindow(batchWindow), maxBatchSize)
+}
+
+// NewRedisBatcherFromURLWithPolicy is NewRedisBatcherFromURL, but lets the
+// caller choose a FlushPolicy instead of a fixed window.
+func NewRedisBatcherFromURLWithPolicy(uri string, poolSize int, enabled bool, policy FlushPolicy, maxBatchSize int) (*RedisBatcher, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL %q: %w", uri, err)
+	}
+
+	addrs := strings.Split(parsed.Host, ",")
+	password, _ := parsed.User.Password()
+
+	switch parsed.Scheme {
+	case "redis", "rediss":
+		opts := &redis.Options{
+			Addr:     parsed.Host,
+			Password: password,
+			PoolSize: poolSize,
+		}
+		if parsed.Scheme == "rediss" {
+			opts.TLSConfig = &tls.Config{}
+		}
+		return newRedisBatcher(redis.NewClient(opts), false, enabled, policy, maxBatchSize), nil
+
+	case "redis-sentinel":
+		client := redis.NewFailoverClient(&redis.FailoverOptions{
+			SentinelAddrs: addrs,
+			MasterName:    parsed.Query().Get("master"),
+			Password:      password,
+			PoolSize:      poolSize,
+		})
+		return newRedisBatcher(client, false, enabled, policy, maxBatchSize), nil
+
+	case "redis-cluster":
+		client := redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: password,
+			PoolSize: poolSize,
+		})
+		return newRedisBatcher(client, true, enabled, policy, maxBatchSize), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported Redis URL scheme %q", parsed.Scheme)
+	}
+}
+
+// NewRedisBatcherFromMode creates a new batcher addressing addrs directly
+// rather than parsing a connection URI, dispatching on mode ("standalone",
+// "sentinel", or "cluster") the same way NewRedisBatcherFromURL dispatches on
+// URL scheme. masterName is only used, and required, in sentinel mode.
+func NewRedisBatcherFromMode(mode string, addrs []string, masterName, password string, poolSize int, enabled bool, policy FlushPolicy, maxBatchSize int) (*RedisBatcher, error) {
+	switch mode {
+	case "", "standalone":
+		if len(addrs) != 1 {
+			return nil, fmt.Errorf("standalone mode requires exactly one address, got %d", len(addrs))
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     addrs[0],
+			Password: password,
+			PoolSize: poolSize,
+		})
+		return newRedisBatcher(client, false, enabled, policy, maxBatchSize), nil
+
+	case "sentinel":
+		if masterName == "" {
+			return nil, fmt.Errorf("sentinel mode requires a master name")
+		}
+		client := redis.NewFailoverClient(&redis.FailoverOptions{
+			SentinelAddrs: addrs,
+			MasterName:    masterName,
+			Password:      password,
+			PoolSize:      poolSize,
+		})
+		return newRedisBatcher(client, false, enabled, policy, maxBatchSize), nil
+
+	case "cluster":
+		client := redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    addrs,
+			Password: password,
+			PoolSize: poolSize,
+		})
+		return newRedisBatcher(client, true, enabled, policy, maxBatchSize), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported Redis mode %q (want standalone, sentinel, or cluster)", mode)
+	}
+}
+
+// NewLevelDBBatcher creates a new batcher backed by an embedded LevelDB
+// database at path instead of a Redis server.
+func NewLevelDBBatcher(path string, enabled bool, batchWindow time.Duration, maxBatchSize int) (*RedisBatcher, error) {
+	backend, err := NewLevelDBBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	return newBatcher(backend, enabled, NewFixedWindow(batchWindow), maxBatchSize), nil
+}
+
+// NewBatcherFromURI creates a new batcher from a connection URI, dispatching
+// on scheme to select the storage backend:
+//
+//	leveldb:///path/to/db               embedded LevelDB
+//	redis://, rediss://, redis-sentinel://, redis-cluster://   see NewRedisBatcherFromURL
+//
+// This lets benchmark configuration pick its storage engine - Redis or
+// LevelDB - without the caller having to know which constructor to call.
+func NewBatcherFromURI(uri string, poolSize int, enabled bool, batchWindow time.Duration, maxBatchSize int) (*RedisBatcher, error) {
+	return NewBatcherFromURIWithPolicy(uri, poolSize, enabled, NewFixedWindow(batchWindow), maxBatchSize)
+}
+
+// NewBatcherFromURIWithPolicy is NewBatcherFromURI, but lets the caller
+// choose a FlushPolicy instead of a fixed window.
+func NewBatcherFromURIWithPolicy(uri string, poolSize int, enabled bool, policy FlushPolicy, maxBatchSize int) (*RedisBatcher, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batcher URL %q: %w", uri, err)
+	}
+
+	if parsed.Scheme == "leveldb" {
+		backend, err := NewLevelDBBackend(parsed.Path)
+		if err != nil {
+			return nil, err
+		}
+		return newBatcher(backend, enabled, policy, maxBatchSize), nil
+	}
+
+	return NewRedisBatcherFromURLWithPolicy(uri, poolSize, enabled, policy, maxBatchSize)
+}
+
+func newRedisBatcher(client redis.UniversalClient, clusterMode bool, enabled bool, policy FlushPolicy, maxBatchSize int) *RedisBatcher {
 	// Verify connection
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		log.Printf("Warning: Redis connection failed: %v", err)
 	} else {
-		log.Printf("Redis connection successful to %s:%s", redisHost, redisPort)
+		log.Printf("Redis connection successful")
+	}
+
+	return newBatcher(NewRedisBackend(client, clusterMode), enabled, policy, maxBatchSize)
+}
+
+func newBatcher(backend Backend, enabled bool, policy FlushPolicy, maxBatchSize int) *RedisBatcher {
+	metrics := NewMetrics()
+	if consumer, ok := backend.(metricsConsumer); ok {
+		consumer.setMetrics(metrics)
 	}
 
 	batcher := &RedisBatcher{
-		client:          client,
+		backend:         backend,
 		enabled:         enabled,
-		batchWindow:     batchWindow,
+		policy:          policy,
 		maxBatchSize:    maxBatchSize,
 		pendingRequests: make(chan *BatchRequest, maxBatchSize*10), // Buffer to handle spikes
+		metrics:         metrics,
 	}
 
 	if enabled {
@@ -71,16 +288,85 @@ func NewRedisBatcher(redisHost string, redisPort string, redisPassword string, p
 	return batcher
 }
 
-// Submit adds a request to the batching queue
-func (b *RedisBatcher) Submit(request *BatchRequest) {
+// Metrics returns the Prometheus collectors for this batcher, so callers can
+// mount Metrics.Handler() (e.g. at /metrics) for scraping.
+func (b *RedisBatcher) Metrics() *Metrics {
+	return b.metrics
+}
+
+// CurrentWindow returns the flush window the configured FlushPolicy would use
+// for the next batch - fixed for FixedWindow, EWMA-derived for AdaptiveWindow
+// - for exposing through a /stats endpoint during load testing.
+func (b *RedisBatcher) CurrentWindow() time.Duration {
+	return b.policy.Window()
+}
+
+// Invalidations returns a channel of keys whose value has changed, for
+// driving eviction of a read-through Cache in front of this batcher, if the
+// underlying Backend supports publishing them (only RedisBackend does).
+// Returns nil otherwise.
+func (b *RedisBatcher) Invalidations() <-chan string {
+	if source, ok := b.backend.(invalidationSource); ok {
+		return source.Invalidations()
+	}
+	return nil
+}
+
+// DedupeStats returns the cumulative number of read requests observed and how
+// many were satisfied by an already in-flight fetch, if the underlying
+// Backend tracks it (only RedisBackend does). Returns 0, 0 otherwise.
+func (b *RedisBatcher) DedupeStats() (total, hits int64) {
+	if source, ok := b.backend.(dedupeStatsSource); ok {
+		return source.DedupeStats()
+	}
+	return 0, 0
+}
+
+// Submit adds a request to the batching queue. The span covering request
+// carries from here through result delivery, regardless of whether it ends
+// up coalesced into a batch or (with batching disabled) executed alone.
+func (b *RedisBatcher) Submit(ctx context.Context, request *BatchRequest) {
+	ctx, span := tracer.Start(ctx, "batching.request", trace.WithAttributes(
+		attribute.String("batching.op", request.Type),
+	))
+	request.spanCtx = span.SpanContext()
+	b.watchDelivery(request, span)
+
 	if !b.enabled {
-		// If batching is disabled, execute the request immediately
-		b.executeRequest(context.Background(), request)
+		// If batching is disabled, execute the request immediately as a
+		// batch of one
+		b.metrics.BatchSize.Observe(1)
+		b.processBatch([]*BatchRequest{request})
 		return
 	}
 
 	// Submit to the batching queue
 	b.pendingRequests <- request
+	b.metrics.QueueDepth.Set(float64(len(b.pendingRequests)))
+}
+
+// watchDelivery substitutes request's result/error channels for internal
+// ones, then forwards whichever fires to the caller's original channels once
+// it arrives - ending span and recording OpErrors at that point, so the span
+// and the metric both cover the request's full lifetime, not just its time
+// in the queue.
+func (b *RedisBatcher) watchDelivery(request *BatchRequest, span trace.Span) {
+	callerResult, callerError := request.ResultChan, request.ErrorChan
+	request.ResultChan = make(chan any, 1)
+	request.ErrorChan = make(chan error, 1)
+
+	go func() {
+		defer span.End()
+		select {
+		case value := <-request.ResultChan:
+			callerResult <- value
+		case err := <-request.ErrorChan:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			b.metrics.OpErrors.WithLabelValues(request.Type).Inc()
+			callerError <- err
+		}
+	}()
 }
 
 // Shutdown stops the batcher and waits for all requests to finish
@@ -89,44 +375,8 @@ func (b *RedisBatcher) Shutdown() {
 		close(b.pendingRequests)
 		b.wg.Wait()
 	}
-	if b.client != nil {
-		b.client.Close()
-	}
-}
-
-// executeRequest executes a single request without batching
-func (b *RedisBatcher) executeRequest(ctx context.Context, request *BatchRequest) {
-	switch request.Type {
-	case TypeGet:
-		result, err := b.client.Get(ctx, request.Key).Result()
-		if err != nil {
-			request.ErrorChan <- err
-		} else {
-			request.ResultChan <- result
-		}
-	case TypeSet:
-		result, err := b.client.Set(ctx, request.Key, request.Value, 0).Result()
-		if err != nil {
-			request.ErrorChan <- err
-		} else {
-			request.ResultChan <- result
-		}
-	case TypeDel:
-		result, err := b.client.Del(ctx, request.Key).Result()
-		if err != nil {
-			request.ErrorChan <- err
-		} else {
-			request.ResultChan <- result
-		}
-	case TypeExists:
-		result, err := b.client.Exists(ctx, request.Key).Result()
-		if err != nil {
-			request.ErrorChan <- err
-		} else {
-			request.ResultChan <- result
-		}
-	default:
-		request.ErrorChan <- fmt.Errorf("unsupported request type: %s", request.Type)
+	if b.backend != nil {
+		b.backend.Close()
 	}
 }
 
@@ -137,20 +387,24 @@ func (b *RedisBatcher) processRequestsLoop() {
 	for {
 		// Create a new batch
 		batch := make([]*BatchRequest, 0, b.maxBatchSize)
-		
+
 		// Wait for first request or exit if channel is closed
 		request, ok := <-b.pendingRequests
 		if !ok {
 			// Channel closed, exit
 			return
 		}
-		
+		b.metrics.QueueDepth.Set(float64(len(b.pendingRequests)))
+
+		start := time.Now()
 		batch = append(batch, request)
-		
-		// Set timer for batch window
-		timer := time.NewTimer(b.batchWindow)
+		stats := BatchStats{Count: 1, Bytes: requestBytes(request)}
+
+		// Set timer for the policy's flush window
+		timer := time.NewTimer(b.policy.Window())
 
-		// Collect requests until batch is full or window expires
+		// Collect requests until batch is full, the policy says to flush, or
+		// the window expires
 	collectLoop:
 		for len(batch) < b.maxBatchSize {
 			select {
@@ -159,7 +413,14 @@ func (b *RedisBatcher) processRequestsLoop() {
 					// Channel closed
 					break collectLoop
 				}
+				b.metrics.QueueDepth.Set(float64(len(b.pendingRequests)))
 				batch = append(batch, request)
+				stats.Count = len(batch)
+				stats.Bytes += requestBytes(request)
+				stats.Elapsed = time.Since(start)
+				if b.policy.ShouldFlush(stats) {
+					break collectLoop
+				}
 			case <-timer.C:
 				// Batch window expired
 				break collectLoop
@@ -175,210 +436,199 @@ func (b *RedisBatcher) processRequestsLoop() {
 			}
 		}
 
-		// Process the batch
-		b.processBatch(batch)
+		stats.Elapsed = time.Since(start)
+		b.policy.Observe(stats)
+		b.metrics.BatchSize.Observe(float64(stats.Count))
+		b.metrics.BatchFillTime.Observe(stats.Elapsed.Seconds())
+
+		// Process the batch. Batches run concurrently with each other so a
+		// slow backend round trip for one batch doesn't stall the next,
+		// which is also why RedisBackend tracks in-flight GET/EXISTS
+		// deduplication across batches rather than just within a single one.
+		b.wg.Add(1)
+		go func(batch []*BatchRequest) {
+			defer b.wg.Done()
+			b.processBatch(batch)
+		}(batch)
 	}
 }
 
-// processBatch processes a batch of requests
+func requestBytes(request *BatchRequest) int {
+	return len(request.Key) + len(request.Value)
+}
+
+// processBatch hands batch to the configured Backend, hedging the call if
+// the policy asks for it. It opens a "batch" span linked to every request
+// span in batch, so a trace backend can show how many requests a single
+// Backend call coalesced - the link points from batch to request, rather
+// than the reverse, because each request's span is already open (started in
+// Submit) by the time the batch exists.
 func (b *RedisBatcher) processBatch(batch []*BatchRequest) {
 	if len(batch) == 0 {
 		return
 	}
 
-	// Group requests by type and parameters for better batching
-	getRequests := make(map[string][]*BatchRequest)
-	setRequests := make(map[string][]*BatchRequest)
-	delRequests := make(map[string][]*BatchRequest)
-	existsRequests := make(map[string][]*BatchRequest)
+	ctx, span := tracer.Start(context.Background(), "batching.processBatch",
+		trace.WithLinks(batchLinks(batch)...),
+		trace.WithAttributes(attribute.Int("batching.batch_size", len(batch))),
+	)
+	defer span.End()
 
-	log.Printf("Processing batch of %d requests", len(batch))
-
-	for _, request := range batch {
-		switch request.Type {
-		case TypeGet:
-			key := request.Key
-			getRequests[key] = append(getRequests[key], request)
-		case TypeSet:
-			key := fmt.Sprintf("%s:%s", request.Key, request.Value)
-			setRequests[key] = append(setRequests[key], request)
-		case TypeDel:
-			key := request.Key
-			delRequests[key] = append(delRequests[key], request)
-		case TypeExists:
-			key := request.Key
-			existsRequests[key] = append(existsRequests[key], request)
-		default:
-			request.ErrorChan <- fmt.Errorf("unsupported request type: %s", request.Type)
-		}
-	}
-
-	ctx := context.Background()
-	
-	// Process all requests using Redis pipelines
-	pipe := b.client.Pipeline()
-	
-	// Process GET requests
-	type GetResult struct {
-		Cmd      *redis.StringCmd
-		Requests []*BatchRequest
+	var deadline time.Time
+	batch, deadline = expireStaleRequests(batch)
+	if len(batch) == 0 {
+		return
 	}
-	getResults := make([]GetResult, 0)
-	for key, requests := range getRequests {
-		cmd := pipe.Get(ctx, key)
-		getResults = append(getResults, GetResult{
-			Cmd:      cmd,
-			Requests: requests,
-		})
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
 	}
-	
-	// Process SET requests
-	type SetResult struct {
-		Cmd      *redis.StatusCmd
-		Requests []*BatchRequest
+
+	if hedger, ok := b.policy.(Hedger); ok {
+		b.processBatchHedged(ctx, batch, hedger.HedgeAfter())
+		return
 	}
-	setResults := make([]SetResult, 0)
-	for keyValue, requests := range setRequests {
-		parts := splitKeyValue(keyValue)
-		if len(parts) == 2 {
-			cmd := pipe.Set(ctx, parts[0], parts[1], 0)
-			setResults = append(setResults, SetResult{
-				Cmd:      cmd,
-				Requests: requests,
-			})
+
+	start := time.Now()
+	err := b.backend.ExecuteBatch(ctx, batch)
+	b.metrics.observeExec(start)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		for _, request := range batch {
+			request.ErrorChan <- err
 		}
 	}
-	
-	// Process DEL requests
-	type DelResult struct {
-		Cmd      *redis.IntCmd
-		Requests []*BatchRequest
-	}
-	delResults := make([]DelResult, 0)
-	for key, requests := range delRequests {
-		cmd := pipe.Del(ctx, key)
-		delResults = append(delResults, DelResult{
-			Cmd:      cmd,
-			Requests: requests,
-		})
-	}
-	
-	// Process EXISTS requests
-	type ExistsResult struct {
-		Cmd      *redis.IntCmd
-		Requests []*BatchRequest
-	}
-	existsResults := make([]ExistsResult, 0)
-	for key, requests := range existsRequests {
-		cmd := pipe.Exists(ctx, key)
-		existsResults = append(existsResults, ExistsResult{
-			Cmd:      cmd,
-			Requests: requests,
-		})
-	}
-	
-	// Execute the pipeline
-	_, err := pipe.Exec(ctx)
-	if err != nil {
-		// If pipeline fails, send error to all requests
-		log.Printf("Redis pipeline execution failed: %v", err)
-		for _, batch := range []interface{}{getResults, setResults, delResults, existsResults} {
-			switch b := batch.(type) {
-			case []GetResult:
-				for _, result := range b {
-					for _, request := range result.Requests {
-						request.ErrorChan <- err
-					}
-				}
-			case []SetResult:
-				for _, result := range b {
-					for _, request := range result.Requests {
-						request.ErrorChan <- err
-					}
-				}
-			case []DelResult:
-				for _, result := range b {
-					for _, request := range result.Requests {
-						request.ErrorChan <- err
-					}
-				}
-			case []ExistsResult:
-				for _, result := range b {
-					for _, request := range result.Requests {
-						request.ErrorChan <- err
-					}
-				}
-			}
+}
+
+// expireStaleRequests drops requests whose Ctx has already expired,
+// delivering ctx.Err() (context.DeadlineExceeded or context.Canceled) on
+// their ErrorChan instead of spending a Backend round trip on them, and
+// returns the earliest Deadline among the remaining requests (zero if none
+// set one) so the caller can bound the batch's execution context by it -
+// ensuring a stuck Backend call cannot pin the batch past any one client's
+// timeout.
+func expireStaleRequests(batch []*BatchRequest) ([]*BatchRequest, time.Time) {
+	live := make([]*BatchRequest, 0, len(batch))
+	var earliest time.Time
+	for _, request := range batch {
+		if request.Ctx == nil {
+			live = append(live, request)
+			continue
+		}
+		if err := request.Ctx.Err(); err != nil {
+			request.ErrorChan <- err
+			continue
+		}
+		live = append(live, request)
+		if deadline, ok := request.Ctx.Deadline(); ok && (earliest.IsZero() || deadline.Before(earliest)) {
+			earliest = deadline
 		}
-		return
 	}
-	
-	// Process results and send responses
-	for _, result := range getResults {
-		val, err := result.Cmd.Result()
-		for _, request := range result.Requests {
-			if err != nil {
-				request.ErrorChan <- err
-			} else {
-				request.ResultChan <- val
-			}
+	return live, earliest
+}
+
+// batchLinks returns a trace.Link to every request in batch that carries a
+// valid span context, for attaching to the enclosing batch span.
+func batchLinks(batch []*BatchRequest) []trace.Link {
+	links := make([]trace.Link, 0, len(batch))
+	for _, request := range batch {
+		if request.spanCtx.IsValid() {
+			links = append(links, trace.Link{SpanContext: request.spanCtx})
 		}
 	}
-	
-	for _, result := range setResults {
-		val, err := result.Cmd.Result()
-		for _, request := range result.Requests {
-			if err != nil {
-				request.ErrorChan <- err
-			} else {
-				request.ResultChan <- val
+	return links
+}
+
+// processBatchHedged executes batch against the Backend, and if that call
+// hasn't completed within hedgeAfter, starts a second, independent
+// ExecuteBatch call for the same batch on a second connection. Each original
+// request is forwarded the result of whichever attempt delivers first; the
+// other attempt's context is cancelled once a winner is known. ctx is the
+// batch span's context, derived from processBatch, so both attempts show up
+// as part of the same trace.
+func (b *RedisBatcher) processBatchHedged(ctx context.Context, batch []*BatchRequest, hedgeAfter time.Duration) {
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	secondaryCtx, cancelSecondary := context.WithCancel(ctx)
+	defer cancelPrimary()
+	defer cancelSecondary()
+
+	primary := shadowBatch(batch)
+	primaryDone := make(chan struct{})
+	go func() {
+		defer close(primaryDone)
+		start := time.Now()
+		err := b.backend.ExecuteBatch(primaryCtx, primary)
+		b.metrics.observeExec(start)
+		if err != nil {
+			// Same contract as processBatch: a non-nil top-level error means
+			// the Backend didn't deliver any per-request results for this
+			// attempt, so every request in it gets err instead.
+			for _, req := range primary {
+				req.ErrorChan <- err
 			}
 		}
-	}
-	
-	for _, result := range delResults {
-		val, err := result.Cmd.Result()
-		for _, request := range result.Requests {
-			if err != nil {
-				request.ErrorChan <- err
-			} else {
-				request.ResultChan <- val
+	}()
+
+	secondary := shadowBatch(batch)
+	go func() {
+		select {
+		case <-primaryDone:
+			return
+		case <-time.After(hedgeAfter):
+		}
+		start := time.Now()
+		err := b.backend.ExecuteBatch(secondaryCtx, secondary)
+		b.metrics.observeExec(start)
+		if err != nil {
+			for _, req := range secondary {
+				req.ErrorChan <- err
 			}
 		}
-	}
-	
-	for _, result := range existsResults {
-		val, err := result.Cmd.Result()
-		for _, request := range result.Requests {
-			if err != nil {
+	}()
+
+	var wg sync.WaitGroup
+	for i, request := range batch {
+		wg.Add(1)
+		go func(request *BatchRequest, primary, secondary *BatchRequest) {
+			defer wg.Done()
+			select {
+			case value := <-primary.ResultChan:
+				cancelSecondary()
+				request.ResultChan <- value
+			case err := <-primary.ErrorChan:
+				cancelSecondary()
+				request.ErrorChan <- err
+			case value := <-secondary.ResultChan:
+				cancelPrimary()
+				request.ResultChan <- value
+			case err := <-secondary.ErrorChan:
+				cancelPrimary()
 				request.ErrorChan <- err
-			} else {
-				request.ResultChan <- val
 			}
-		}
+		}(request, primary[i], secondary[i])
 	}
-	
-	log.Printf("Batch processing completed for %d requests", len(batch))
+	wg.Wait()
 }
 
-// Helper function to split key:value format
-func splitKeyValue(keyValue string) []string {
-	var parts []string
-	inKey := true
-	var key, value string
-	
-	for i := 0; i < len(keyValue); i++ {
-		if keyValue[i] == ':' && inKey {
-			inKey = false
-			continue
-		}
-		
-		if inKey {
-			key += string(keyValue[i])
-		} else {
-			value += string(keyValue[i])
+// shadowBatch copies batch into fresh BatchRequests, with their own buffered
+// result/error channels, that can be handed to a second concurrent Backend
+// call without racing on the original requests' channels.
+func shadowBatch(batch []*BatchRequest) []*BatchRequest {
+	shadow := make([]*BatchRequest, len(batch))
+	for i, request := range batch {
+		shadow[i] = &BatchRequest{
+			Type:       request.Type,
+			Key:        request.Key,
+			Value:      request.Value,
+			TTL:        request.TTL,
+			Amount:     request.Amount,
+			Field:      request.Field,
+			Ctx:        request.Ctx,
+			ResultChan: make(chan any, 1),
+			ErrorChan:  make(chan error, 1),
 		}
 	}
-	
-	return []string{key, value}
-} 
\ No newline at end of file
+	return shadow
+}
@@ -0,0 +1,117 @@
+package batching
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a single Cache node, tracked in the LRU list in addition to
+// the key->element map so eviction can walk from least- to most-recently-used.
+type cacheEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+	size      int64
+}
+
+// Cache is an in-process, read-through cache for GET results, bounded by
+// total byte size (key+value, across all entries) rather than entry count,
+// with both LRU eviction and a per-entry TTL. It does not talk to Redis
+// itself - RedisBatcher.Invalidations feeds it the keys to evict.
+type Cache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	maxBytes  int64
+	usedBytes int64
+	ll        *list.List
+	items     map[string]*list.Element
+	hits      int64
+	misses    int64
+}
+
+// NewCache creates a Cache that holds at most maxBytes worth of key+value
+// data, evicting entries older than ttl (checked lazily, on Get) or least
+// recently used once maxBytes is exceeded.
+func NewCache(maxBytes int64, ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:      ttl,
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value for key and true, if present and not expired.
+func (c *Cache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return "", false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		c.misses++
+		return "", false
+	}
+
+	c.ll.MoveToFront(elem)
+	c.hits++
+	return entry.value, true
+}
+
+// Set inserts or refreshes key's cached value, evicting the least recently
+// used entries if needed to stay within maxBytes.
+func (c *Cache) Set(key, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.items[key]; ok {
+		c.removeElement(existing)
+	}
+
+	entry := &cacheEntry{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+		size:      int64(len(key) + len(value)),
+	}
+	elem := c.ll.PushFront(entry)
+	c.items[key] = elem
+	c.usedBytes += entry.size
+
+	for c.usedBytes > c.maxBytes && c.ll.Len() > 0 {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Invalidate evicts key, if present, regardless of its TTL.
+func (c *Cache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// removeElement removes elem from both the LRU list and the lookup map.
+// Callers must hold c.mu.
+func (c *Cache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.ll.Remove(elem)
+	delete(c.items, entry.key)
+	c.usedBytes -= entry.size
+}
+
+// Stats returns the cumulative hit and miss counts observed by Get.
+func (c *Cache) Stats() (hits, misses int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
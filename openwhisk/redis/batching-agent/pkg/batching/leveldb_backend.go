@@ -0,0 +1,94 @@
+package batching
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// LevelDBBackend is a Backend implementation that stores keys in an embedded
+// LevelDB database instead of Redis. It exists so the benchmark can run
+// without a Redis server and so batching gains can be compared across
+// storage engines.
+type LevelDBBackend struct {
+	db *leveldb.DB
+}
+
+// NewLevelDBBackend opens (creating if necessary) a LevelDB database at path.
+func NewLevelDBBackend(path string) (*LevelDBBackend, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open LevelDB at %s: %w", path, err)
+	}
+	return &LevelDBBackend{db: db}, nil
+}
+
+// Close closes the underlying LevelDB database.
+func (l *LevelDBBackend) Close() error {
+	return l.db.Close()
+}
+
+// ExecuteBatch applies every request in batch against the LevelDB database.
+// Reads are served directly; writes (SET/DEL) are accumulated into a single
+// leveldb.Batch so they commit atomically and in one disk write.
+func (l *LevelDBBackend) ExecuteBatch(ctx context.Context, batch []*BatchRequest) error {
+	var writes leveldb.Batch
+	var setRequests, delRequests []*BatchRequest
+
+	for _, request := range batch {
+		switch request.Type {
+		case TypeGet:
+			value, err := l.db.Get([]byte(request.Key), nil)
+			if err != nil {
+				if err == leveldb.ErrNotFound {
+					request.ResultChan <- nil
+				} else {
+					request.ErrorChan <- err
+				}
+				continue
+			}
+			request.ResultChan <- string(value)
+		case TypeSet:
+			writes.Put([]byte(request.Key), []byte(request.Value))
+			setRequests = append(setRequests, request)
+		case TypeDel:
+			writes.Delete([]byte(request.Key))
+			delRequests = append(delRequests, request)
+		case TypeExists:
+			_, err := l.db.Get([]byte(request.Key), nil)
+			switch {
+			case err == nil:
+				request.ResultChan <- int64(1)
+			case err == leveldb.ErrNotFound:
+				request.ResultChan <- int64(0)
+			default:
+				request.ErrorChan <- err
+			}
+		default:
+			request.ErrorChan <- fmt.Errorf("unsupported request type: %s", request.Type)
+		}
+	}
+
+	if len(setRequests) == 0 && len(delRequests) == 0 {
+		return nil
+	}
+
+	if err := l.db.Write(&writes, nil); err != nil {
+		for _, request := range setRequests {
+			request.ErrorChan <- err
+		}
+		for _, request := range delRequests {
+			request.ErrorChan <- err
+		}
+		return nil
+	}
+
+	for _, request := range setRequests {
+		request.ResultChan <- "OK"
+	}
+	for _, request := range delRequests {
+		request.ResultChan <- int64(1)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package batching
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors for a single RedisBatcher's
+// pipeline. Each RedisBatcher gets its own Metrics registered to its own
+// registry, rather than a shared global one, so running more than one
+// batcher in the same process doesn't panic on duplicate metric
+// registration.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	QueueDepth    prometheus.Gauge
+	BatchSize     prometheus.Histogram
+	BatchFillTime prometheus.Histogram
+	ExecDuration  prometheus.Histogram
+	OpErrors      *prometheus.CounterVec
+	DedupeHits    prometheus.Counter
+}
+
+// NewMetrics creates and registers a fresh set of collectors.
+func NewMetrics() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		registry: registry,
+		QueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "batching_queue_depth",
+			Help: "Number of requests currently waiting in the batching queue.",
+		}),
+		BatchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "batching_batch_size",
+			Help:    "Number of requests coalesced into each batch.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		BatchFillTime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "batching_batch_fill_time_seconds",
+			Help:    "Time from a batch's first request to its flush.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		ExecDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "batching_exec_duration_seconds",
+			Help:    "Duration of each Backend.ExecuteBatch call.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		OpErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "batching_op_errors_total",
+			Help: "Errors delivered to requests, by operation type.",
+		}, []string{"op"}),
+		DedupeHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "batching_dedupe_hits_total",
+			Help: "GET/EXISTS requests satisfied by an already in-flight fetch instead of a new Backend call.",
+		}),
+	}
+
+	registry.MustRegister(m.QueueDepth, m.BatchSize, m.BatchFillTime, m.ExecDuration, m.OpErrors, m.DedupeHits)
+	return m
+}
+
+// Handler returns an http.Handler serving these metrics in the Prometheus
+// exposition format, suitable for mounting at e.g. /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) observeExec(start time.Time) {
+	m.ExecDuration.Observe(time.Since(start).Seconds())
+}
+
+// metricsConsumer is implemented by Backends that want the owning
+// RedisBatcher's Metrics wired in once they exist, for stats - like
+// RedisBackend's dedupe hits - that only the Backend itself can observe.
+// newBatcher checks for this the same way processBatch checks FlushPolicy
+// for Hedger: an optional interface, tested with a type assertion, rather
+// than growing the Backend interface for a capability most Backends don't
+// have.
+type metricsConsumer interface {
+	setMetrics(m *Metrics)
+}
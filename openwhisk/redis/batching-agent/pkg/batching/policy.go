@@ -0,0 +1,147 @@
+package batching
+
+import (
+	"sync"
+	"time"
+)
+
+// BatchStats describes a batch as it is being assembled (for ShouldFlush) or
+// once it has been handed to the Backend (for Observe).
+type BatchStats struct {
+	Count   int           // requests collected so far
+	Bytes   int           // cumulative key+value bytes collected so far
+	Elapsed time.Duration // time since the first request landed in the batch
+}
+
+// FlushPolicy decides when processRequestsLoop should stop collecting
+// requests into the current batch and flush it to the Backend. maxBatchSize
+// is always enforced by the caller on top of whatever a FlushPolicy decides.
+type FlushPolicy interface {
+	// Window returns how long to wait, from the first request landing in a
+	// batch, before flushing it regardless of size.
+	Window() time.Duration
+	// ShouldFlush is consulted after every request is added to the batch and
+	// may force an early flush, e.g. once a byte budget is exceeded.
+	ShouldFlush(stats BatchStats) bool
+	// Observe is called once a batch has been handed to the Backend so
+	// adaptive policies can learn from it.
+	Observe(stats BatchStats)
+}
+
+// Hedger is implemented by FlushPolicies that also want processBatch to race
+// a duplicate Backend call if the first one is slow to return.
+type Hedger interface {
+	// HedgeAfter returns how long processBatch should wait for the primary
+	// Backend call before issuing a duplicate one on a second connection.
+	HedgeAfter() time.Duration
+}
+
+// FixedWindow flushes once window has elapsed since the first request in the
+// batch. This is the original RedisBatcher behavior.
+type FixedWindow struct {
+	window time.Duration
+}
+
+// NewFixedWindow returns a FlushPolicy that always waits exactly window
+// before flushing.
+func NewFixedWindow(window time.Duration) *FixedWindow {
+	return &FixedWindow{window: window}
+}
+
+func (p *FixedWindow) Window() time.Duration             { return p.window }
+func (p *FixedWindow) ShouldFlush(stats BatchStats) bool { return false }
+func (p *FixedWindow) Observe(stats BatchStats)          {}
+
+// AdaptiveWindow sizes the flush window using an EWMA so batch latency
+// stays near targetP99: it shrinks the window when batches are taking
+// longer than targetP99 to fill (so the next batch flushes sooner) and
+// grows it back towards maxWindow when traffic is sparse.
+type AdaptiveWindow struct {
+	mu        sync.Mutex
+	window    time.Duration
+	minWindow time.Duration
+	maxWindow time.Duration
+	targetP99 time.Duration
+	alpha     float64
+}
+
+// NewAdaptiveWindow returns a FlushPolicy whose window adapts between
+// minWindow and maxWindow to keep batch fill time near targetP99.
+func NewAdaptiveWindow(minWindow, maxWindow, targetP99 time.Duration) *AdaptiveWindow {
+	return &AdaptiveWindow{
+		window:    maxWindow,
+		minWindow: minWindow,
+		maxWindow: maxWindow,
+		targetP99: targetP99,
+		alpha:     0.2,
+	}
+}
+
+func (p *AdaptiveWindow) Window() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.window
+}
+
+func (p *AdaptiveWindow) ShouldFlush(stats BatchStats) bool { return false }
+
+// Observe nudges the window towards minWindow when the batch it just
+// flushed took longer than targetP99 to fill, and back towards maxWindow
+// otherwise, smoothing the change with an EWMA so a single noisy batch
+// doesn't swing the window.
+func (p *AdaptiveWindow) Observe(stats BatchStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target := p.window
+	if stats.Elapsed > p.targetP99 {
+		target = p.window / 2
+	} else {
+		target = p.window + (p.maxWindow-p.window)/4
+	}
+	if target < p.minWindow {
+		target = p.minWindow
+	}
+	if target > p.maxWindow {
+		target = p.maxWindow
+	}
+	p.window = time.Duration(p.alpha*float64(target) + (1-p.alpha)*float64(p.window))
+}
+
+// ByteBudget wraps another FlushPolicy and additionally forces a flush once
+// the batch's cumulative key+value size reaches maxBytes, keeping the
+// Backend's per-call payload size bounded regardless of request count.
+type ByteBudget struct {
+	FlushPolicy
+	maxBytes int
+}
+
+// NewByteBudget wraps inner so the batch also flushes once it holds maxBytes
+// of cumulative key+value data.
+func NewByteBudget(inner FlushPolicy, maxBytes int) *ByteBudget {
+	return &ByteBudget{FlushPolicy: inner, maxBytes: maxBytes}
+}
+
+func (p *ByteBudget) ShouldFlush(stats BatchStats) bool {
+	if stats.Bytes >= p.maxBytes {
+		return true
+	}
+	return p.FlushPolicy.ShouldFlush(stats)
+}
+
+// HedgedFlush wraps another FlushPolicy and marks the batcher as a Hedger:
+// processBatch will race a duplicate Backend call on a second connection if
+// the first hasn't returned within hedgeAfter, delivering whichever finishes
+// first and cancelling the loser.
+type HedgedFlush struct {
+	FlushPolicy
+	hedgeAfter time.Duration
+}
+
+// NewHedgedFlush wraps inner so processBatch hedges its Backend call after
+// hedgeAfter.
+func NewHedgedFlush(inner FlushPolicy, hedgeAfter time.Duration) *HedgedFlush {
+	return &HedgedFlush{FlushPolicy: inner, hedgeAfter: hedgeAfter}
+}
+
+func (p *HedgedFlush) HedgeAfter() time.Duration { return p.hedgeAfter }
@@ -0,0 +1,711 @@
+package batching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Lua scripts that perform a whole batch of same-type operations atomically
+// in a single round trip. Each script preserves submission order in its
+// returned array (nil entries are kept for missing keys) so results can be
+// fanned back out by index. Scripts that need two ARGV values per key (e.g.
+// HSet's field and value) lay them out as two concatenated columns, each
+// #KEYS long, rather than interleaving them.
+const (
+	luaScriptMGet = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('GET', k)
+end
+return r
+`
+	luaScriptMSet = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	redis.call('SET', k, ARGV[i])
+	r[i] = 'OK'
+end
+return r
+`
+	luaScriptMDel = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('DEL', k)
+end
+return r
+`
+	luaScriptMExists = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('EXISTS', k)
+end
+return r
+`
+	luaScriptSetEx = `
+local n = #KEYS
+local r = {}
+for i, k in ipairs(KEYS) do
+	redis.call('SET', k, ARGV[i], 'EX', ARGV[n+i])
+	r[i] = 'OK'
+end
+return r
+`
+	luaScriptExpire = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('EXPIRE', k, ARGV[i])
+end
+return r
+`
+	luaScriptIncrBy = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('INCRBY', k, ARGV[i])
+end
+return r
+`
+	luaScriptHGet = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('HGET', k, ARGV[i])
+end
+return r
+`
+	luaScriptHSet = `
+local n = #KEYS
+local r = {}
+for i, k in ipairs(KEYS) do
+	redis.call('HSET', k, ARGV[i], ARGV[n+i])
+	r[i] = 'OK'
+end
+return r
+`
+	luaScriptHDel = `
+local r = {}
+for i, k in ipairs(KEYS) do
+	r[i] = redis.call('HDEL', k, ARGV[i])
+end
+return r
+`
+)
+
+// RedisBackend is the Backend implementation that talks to a real Redis
+// deployment (single node, Sentinel, or Cluster) via go-redis, batching
+// same-type operations into a single Lua script round trip and deduplicating
+// concurrent GET/EXISTS/HGET requests for the same read.
+type RedisBackend struct {
+	client      redis.UniversalClient
+	clusterMode bool
+	mu          sync.Mutex
+
+	scriptSHAs   map[string]string
+	scriptSHAsMu sync.Mutex
+
+	inflight    map[string]*inflightGet // namespace\x00dedupeKey -> fetch already in flight, guarded by mu
+	dedupeHits  int64                   // requests satisfied by an in-flight fetch, guarded by mu
+	dedupeTotal int64                   // read requests observed, guarded by mu
+
+	metrics *Metrics // wired in by newBatcher via setMetrics, nil until then
+
+	invalidationChannel string
+	invalidationsOnce   sync.Once
+	invalidations       chan string
+}
+
+// defaultInvalidationChannel is the pub/sub channel RedisBackend publishes a
+// key to after every write that changes what GET would return, and
+// subscribes to so a read-through Cache can evict that key - including keys
+// written by a different agent instance sharing the same Redis deployment.
+const defaultInvalidationChannel = "redis-batching-agent:invalidate"
+
+// inflightGet tracks a single read round trip to Redis so that duplicate
+// requests for the same read - whether they land in the same batch or in a
+// batch that is already executing - are served by one Redis call.
+type inflightGet struct {
+	waiters []*BatchRequest
+	value   interface{}
+	err     error
+}
+
+// fetchEntry groups every request asking for the same read. dedupeKey (the
+// map key entries are stored under) must uniquely identify what's being
+// fetched - just the Redis key for GET/EXISTS, but "key\x00field" for HGET,
+// since two different fields on the same hash are not interchangeable.
+type fetchEntry struct {
+	Key      string      // Redis key
+	Argv     interface{} // per-key ARGV value (e.g. hash field); nil if the script takes no ARGV
+	Requests []*BatchRequest
+}
+
+// setEntry groups every request asking to set the same key to the same value
+type setEntry struct {
+	Key      string
+	Value    string
+	Requests []*BatchRequest
+}
+
+// setExEntry groups every request asking to SETEX the same key to the same
+// value with the same TTL.
+type setExEntry struct {
+	Key        string
+	Value      string
+	TTLSeconds int64
+	Requests   []*BatchRequest
+}
+
+// expireEntry groups every request asking to EXPIRE the same key with the
+// same TTL.
+type expireEntry struct {
+	Key        string
+	TTLSeconds int64
+	Requests   []*BatchRequest
+}
+
+// incrByEntry groups every request asking to INCRBY the same key by the same
+// delta (Incr/Decr/IncrBy all resolve to one of these via incrDelta).
+type incrByEntry struct {
+	Key      string
+	Delta    int64
+	Requests []*BatchRequest
+}
+
+// hsetEntry groups every request asking to HSET the same field on the same
+// hash to the same value.
+type hsetEntry struct {
+	Key      string
+	Field    string
+	Value    string
+	Requests []*BatchRequest
+}
+
+// hdelEntry groups every request asking to HDEL the same field from the same
+// hash.
+type hdelEntry struct {
+	Key      string
+	Field    string
+	Requests []*BatchRequest
+}
+
+// NewRedisBackend wraps an already-connected redis.UniversalClient. clusterMode
+// must be true when client is a *redis.ClusterClient so multi-key scripts are
+// sharded by hash slot instead of sent as a single EVALSHA.
+func NewRedisBackend(client redis.UniversalClient, clusterMode bool) *RedisBackend {
+	return &RedisBackend{
+		client:              client,
+		clusterMode:         clusterMode,
+		scriptSHAs:          make(map[string]string),
+		inflight:            make(map[string]*inflightGet),
+		invalidationChannel: defaultInvalidationChannel,
+	}
+}
+
+// Close closes the underlying Redis client connection(s).
+func (r *RedisBackend) Close() error {
+	return r.client.Close()
+}
+
+// Invalidations implements invalidationSource. It lazily subscribes to this
+// backend's invalidation channel the first time it's called, and forwards
+// every message - whether published by this backend's own writes or by
+// another agent instance sharing the channel - to the returned channel.
+func (r *RedisBackend) Invalidations() <-chan string {
+	r.invalidationsOnce.Do(func() {
+		r.invalidations = make(chan string, 256)
+		pubsub := r.client.Subscribe(context.Background(), r.invalidationChannel)
+		go func() {
+			defer close(r.invalidations)
+			for msg := range pubsub.Channel() {
+				r.invalidations <- msg.Payload
+			}
+		}()
+	})
+	return r.invalidations
+}
+
+// publishInvalidation announces that keys have changed, for any Cache
+// subscribed to this backend's invalidation channel to evict.
+func (r *RedisBackend) publishInvalidation(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := r.client.Publish(ctx, r.invalidationChannel, key).Err(); err != nil {
+			log.Printf("Failed to publish cache invalidation for %q: %v", key, err)
+		}
+	}
+}
+
+// setMetrics implements metricsConsumer, letting newBatcher wire in the
+// owning RedisBatcher's Metrics after both are constructed.
+func (r *RedisBackend) setMetrics(m *Metrics) {
+	r.metrics = m
+}
+
+// incrDelta returns the INCRBY delta for an Incr/Decr/IncrBy request: Incr
+// and Decr always move by 1, IncrBy uses the caller-supplied Amount.
+func incrDelta(request *BatchRequest) int64 {
+	switch request.Type {
+	case TypeIncr:
+		return 1
+	case TypeDecr:
+		return -1
+	default: // TypeIncrBy
+		return request.Amount
+	}
+}
+
+// ExecuteBatch processes a batch of requests. Instead of pipelining one
+// command per request, it groups requests by type and parameters and runs a
+// single Lua script per group so the whole group round-trips to Redis once.
+func (r *RedisBackend) ExecuteBatch(ctx context.Context, batch []*BatchRequest) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	getRequests := make(map[string]*fetchEntry)
+	existsRequests := make(map[string]*fetchEntry)
+	hgetRequests := make(map[string]*fetchEntry)
+	setRequests := make(map[string]*setEntry)
+	delRequests := make(map[string][]*BatchRequest)
+	setExRequests := make(map[string]*setExEntry)
+	expireRequests := make(map[string]*expireEntry)
+	incrByRequests := make(map[string]*incrByEntry)
+	hsetRequests := make(map[string]*hsetEntry)
+	hdelRequests := make(map[string]*hdelEntry)
+
+	log.Printf("Processing batch of %d requests", len(batch))
+
+	for _, request := range batch {
+		switch request.Type {
+		case TypeGet:
+			groupFetch(getRequests, request.Key, nil, request)
+		case TypeExists:
+			groupFetch(existsRequests, request.Key, nil, request)
+		case TypeHGet:
+			groupFetch(hgetRequests, request.Key+"\x00"+request.Field, request.Field, request)
+
+		case TypeSet:
+			groupKey := fmt.Sprintf("%s\x00%s", request.Key, request.Value)
+			entry, ok := setRequests[groupKey]
+			if !ok {
+				entry = &setEntry{Key: request.Key, Value: request.Value}
+				setRequests[groupKey] = entry
+			}
+			entry.Requests = append(entry.Requests, request)
+
+		case TypeDel:
+			delRequests[request.Key] = append(delRequests[request.Key], request)
+
+		case TypeSetEx:
+			ttlSeconds := int64(request.TTL.Seconds())
+			groupKey := fmt.Sprintf("%s\x00%s\x00%d", request.Key, request.Value, ttlSeconds)
+			entry, ok := setExRequests[groupKey]
+			if !ok {
+				entry = &setExEntry{Key: request.Key, Value: request.Value, TTLSeconds: ttlSeconds}
+				setExRequests[groupKey] = entry
+			}
+			entry.Requests = append(entry.Requests, request)
+
+		case TypeExpire:
+			ttlSeconds := int64(request.TTL.Seconds())
+			groupKey := fmt.Sprintf("%s\x00%d", request.Key, ttlSeconds)
+			entry, ok := expireRequests[groupKey]
+			if !ok {
+				entry = &expireEntry{Key: request.Key, TTLSeconds: ttlSeconds}
+				expireRequests[groupKey] = entry
+			}
+			entry.Requests = append(entry.Requests, request)
+
+		case TypeIncr, TypeDecr, TypeIncrBy:
+			delta := incrDelta(request)
+			groupKey := fmt.Sprintf("%s\x00%d", request.Key, delta)
+			entry, ok := incrByRequests[groupKey]
+			if !ok {
+				entry = &incrByEntry{Key: request.Key, Delta: delta}
+				incrByRequests[groupKey] = entry
+			}
+			entry.Requests = append(entry.Requests, request)
+
+		case TypeHSet:
+			groupKey := fmt.Sprintf("%s\x00%s\x00%s", request.Key, request.Field, request.Value)
+			entry, ok := hsetRequests[groupKey]
+			if !ok {
+				entry = &hsetEntry{Key: request.Key, Field: request.Field, Value: request.Value}
+				hsetRequests[groupKey] = entry
+			}
+			entry.Requests = append(entry.Requests, request)
+
+		case TypeHDel:
+			groupKey := fmt.Sprintf("%s\x00%s", request.Key, request.Field)
+			entry, ok := hdelRequests[groupKey]
+			if !ok {
+				entry = &hdelEntry{Key: request.Key, Field: request.Field}
+				hdelRequests[groupKey] = entry
+			}
+			entry.Requests = append(entry.Requests, request)
+
+		default:
+			request.ErrorChan <- fmt.Errorf("unsupported request type: %s", request.Type)
+		}
+	}
+
+	if len(getRequests) > 0 {
+		r.runDedupedFetch(ctx, TypeGet, luaScriptMGet, getRequests)
+	}
+	if len(existsRequests) > 0 {
+		r.runDedupedFetch(ctx, TypeExists, luaScriptMExists, existsRequests)
+	}
+	if len(hgetRequests) > 0 {
+		r.runDedupedFetch(ctx, TypeHGet, luaScriptHGet, hgetRequests)
+	}
+
+	if len(setRequests) > 0 {
+		keys := make([]string, 0, len(setRequests))
+		values := make([]interface{}, 0, len(setRequests))
+		groups := make([][]*BatchRequest, 0, len(setRequests))
+		for _, entry := range setRequests {
+			keys = append(keys, entry.Key)
+			values = append(values, entry.Value)
+			groups = append(groups, entry.Requests)
+		}
+		r.runGroupedScript(ctx, luaScriptMSet, keys, [][]interface{}{values}, groups)
+		r.publishInvalidation(ctx, keys)
+	}
+
+	if len(delRequests) > 0 {
+		keys := make([]string, 0, len(delRequests))
+		groups := make([][]*BatchRequest, 0, len(delRequests))
+		for key, requests := range delRequests {
+			keys = append(keys, key)
+			groups = append(groups, requests)
+		}
+		r.runGroupedScript(ctx, luaScriptMDel, keys, nil, groups)
+		r.publishInvalidation(ctx, keys)
+	}
+
+	if len(setExRequests) > 0 {
+		keys := make([]string, 0, len(setExRequests))
+		values := make([]interface{}, 0, len(setExRequests))
+		ttls := make([]interface{}, 0, len(setExRequests))
+		groups := make([][]*BatchRequest, 0, len(setExRequests))
+		for _, entry := range setExRequests {
+			keys = append(keys, entry.Key)
+			values = append(values, entry.Value)
+			ttls = append(ttls, entry.TTLSeconds)
+			groups = append(groups, entry.Requests)
+		}
+		r.runGroupedScript(ctx, luaScriptSetEx, keys, [][]interface{}{values, ttls}, groups)
+		r.publishInvalidation(ctx, keys)
+	}
+
+	if len(expireRequests) > 0 {
+		keys := make([]string, 0, len(expireRequests))
+		ttls := make([]interface{}, 0, len(expireRequests))
+		groups := make([][]*BatchRequest, 0, len(expireRequests))
+		for _, entry := range expireRequests {
+			keys = append(keys, entry.Key)
+			ttls = append(ttls, entry.TTLSeconds)
+			groups = append(groups, entry.Requests)
+		}
+		r.runGroupedScript(ctx, luaScriptExpire, keys, [][]interface{}{ttls}, groups)
+	}
+
+	if len(incrByRequests) > 0 {
+		keys := make([]string, 0, len(incrByRequests))
+		deltas := make([]interface{}, 0, len(incrByRequests))
+		groups := make([][]*BatchRequest, 0, len(incrByRequests))
+		for _, entry := range incrByRequests {
+			keys = append(keys, entry.Key)
+			deltas = append(deltas, entry.Delta)
+			groups = append(groups, entry.Requests)
+		}
+		r.runGroupedScript(ctx, luaScriptIncrBy, keys, [][]interface{}{deltas}, groups)
+		r.publishInvalidation(ctx, keys)
+	}
+
+	if len(hsetRequests) > 0 {
+		keys := make([]string, 0, len(hsetRequests))
+		fields := make([]interface{}, 0, len(hsetRequests))
+		values := make([]interface{}, 0, len(hsetRequests))
+		groups := make([][]*BatchRequest, 0, len(hsetRequests))
+		for _, entry := range hsetRequests {
+			keys = append(keys, entry.Key)
+			fields = append(fields, entry.Field)
+			values = append(values, entry.Value)
+			groups = append(groups, entry.Requests)
+		}
+		r.runGroupedScript(ctx, luaScriptHSet, keys, [][]interface{}{fields, values}, groups)
+	}
+
+	if len(hdelRequests) > 0 {
+		keys := make([]string, 0, len(hdelRequests))
+		fields := make([]interface{}, 0, len(hdelRequests))
+		groups := make([][]*BatchRequest, 0, len(hdelRequests))
+		for _, entry := range hdelRequests {
+			keys = append(keys, entry.Key)
+			fields = append(fields, entry.Field)
+			groups = append(groups, entry.Requests)
+		}
+		r.runGroupedScript(ctx, luaScriptHDel, keys, [][]interface{}{fields}, groups)
+	}
+
+	log.Printf("Batch processing completed for %d requests", len(batch))
+	return nil
+}
+
+// groupFetch adds request to entries under dedupeKey, creating the fetchEntry
+// (with Redis key and per-key ARGV value) the first time dedupeKey is seen.
+func groupFetch(entries map[string]*fetchEntry, dedupeKey string, argv interface{}, request *BatchRequest) {
+	entry, ok := entries[dedupeKey]
+	if !ok {
+		entry = &fetchEntry{Key: request.Key, Argv: argv}
+		entries[dedupeKey] = entry
+	}
+	entry.Requests = append(entry.Requests, request)
+}
+
+// runDedupedFetch runs a read-only script (GET/EXISTS/HGET) once per
+// distinct dedupeKey in entries, folding in any request whose dedupeKey is
+// already being fetched by another in-flight batch so only one Redis call
+// is ever outstanding per dedupeKey. namespace separates different scripts'
+// in-flight tracking (e.g. GET vs EXISTS) since they return different
+// results for the same key.
+func (r *RedisBackend) runDedupedFetch(ctx context.Context, namespace, script string, entries map[string]*fetchEntry) {
+	owned := make(map[string]*inflightGet, len(entries))
+	dedupeKeys := make([]string, 0, len(entries))
+	keys := make([]string, 0, len(entries))
+	var argv []interface{}
+
+	r.mu.Lock()
+	for dedupeKey, entry := range entries {
+		r.dedupeTotal += int64(len(entry.Requests))
+		inflightKey := namespace + "\x00" + dedupeKey
+		if existing, ok := r.inflight[inflightKey]; ok {
+			existing.waiters = append(existing.waiters, entry.Requests...)
+			r.dedupeHits += int64(len(entry.Requests))
+			if r.metrics != nil {
+				r.metrics.DedupeHits.Add(float64(len(entry.Requests)))
+			}
+			continue
+		}
+		fresh := &inflightGet{waiters: entry.Requests}
+		r.inflight[inflightKey] = fresh
+		owned[dedupeKey] = fresh
+		dedupeKeys = append(dedupeKeys, dedupeKey)
+		keys = append(keys, entry.Key)
+		if entry.Argv != nil {
+			argv = append(argv, entry.Argv)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(keys) == 0 {
+		return
+	}
+
+	results, err := r.evalScript(ctx, script, keys, argv)
+
+	r.mu.Lock()
+	for i, dedupeKey := range dedupeKeys {
+		entry := owned[dedupeKey]
+		if err != nil {
+			entry.err = err
+		} else if i < len(results) {
+			entry.value = results[i]
+		}
+		delete(r.inflight, namespace+"\x00"+dedupeKey)
+	}
+	r.mu.Unlock()
+
+	for _, entry := range owned {
+		for _, request := range entry.waiters {
+			if entry.err != nil {
+				request.ErrorChan <- entry.err
+			} else {
+				request.ResultChan <- entry.value
+			}
+		}
+	}
+}
+
+// DedupeStats returns the cumulative number of read requests observed and
+// how many of those were satisfied by piggy-backing on an already in-flight
+// fetch instead of issuing a new Redis call.
+func (r *RedisBackend) DedupeStats() (total, hits int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dedupeTotal, r.dedupeHits
+}
+
+// runGroupedScript executes script against keys (with parallel argvCols, if
+// any, each concatenated into the flat ARGV the script indexes by column -
+// see the luaScriptSetEx/luaScriptHSet comment) and fans each result back
+// out, by index, to every request in the matching group. A nil result
+// (missing key) is delivered as a nil value so callers can distinguish
+// "not found" from an error.
+func (r *RedisBackend) runGroupedScript(ctx context.Context, script string, keys []string, argvCols [][]interface{}, groups [][]*BatchRequest) {
+	var argv []interface{}
+	for _, col := range argvCols {
+		argv = append(argv, col...)
+	}
+
+	results, err := r.evalScript(ctx, script, keys, argv)
+	if err != nil {
+		log.Printf("Redis script execution failed: %v", err)
+		for _, requests := range groups {
+			for _, request := range requests {
+				request.ErrorChan <- err
+			}
+		}
+		return
+	}
+
+	for i, requests := range groups {
+		var value interface{}
+		if i < len(results) {
+			value = results[i]
+		}
+		for _, request := range requests {
+			request.ResultChan <- value
+		}
+	}
+}
+
+// evalScript runs script via EVALSHA, loading and caching its SHA1 on first
+// use, and transparently falling back to EVAL if Redis has evicted the
+// script (NOSCRIPT).
+func (r *RedisBackend) evalScript(ctx context.Context, script string, keys []string, argv []interface{}) ([]interface{}, error) {
+	if !r.clusterMode || len(keys) <= 1 {
+		return r.evalScriptSingle(ctx, script, keys, argv)
+	}
+	return r.evalScriptSharded(ctx, script, keys, argv)
+}
+
+// evalScriptSharded splits keys (and their parallel argv, if any) by Redis
+// Cluster hash slot and runs one EVALSHA per slot, since a script spanning
+// multiple keys fails with CROSSSLOT unless every key maps to the same
+// node. Per-slot results are stitched back together in the caller's
+// original key order.
+func (r *RedisBackend) evalScriptSharded(ctx context.Context, script string, keys []string, argv []interface{}) ([]interface{}, error) {
+	indicesBySlot := make(map[uint16][]int)
+	for i, key := range keys {
+		slot := clusterKeySlot(key)
+		indicesBySlot[slot] = append(indicesBySlot[slot], i)
+	}
+
+	results := make([]interface{}, len(keys))
+	for _, indices := range indicesBySlot {
+		slotKeys := make([]string, len(indices))
+		var slotArgv []interface{}
+		if argv != nil {
+			slotArgv = make([]interface{}, len(indices))
+		}
+		for j, idx := range indices {
+			slotKeys[j] = keys[idx]
+			if argv != nil {
+				slotArgv[j] = argv[idx]
+			}
+		}
+
+		slotResults, err := r.evalScriptSingle(ctx, script, slotKeys, slotArgv)
+		if err != nil {
+			return nil, err
+		}
+		for j, idx := range indices {
+			if j < len(slotResults) {
+				results[idx] = slotResults[j]
+			}
+		}
+	}
+	return results, nil
+}
+
+func (r *RedisBackend) evalScriptSingle(ctx context.Context, script string, keys []string, argv []interface{}) ([]interface{}, error) {
+	sha := r.loadScript(ctx, script)
+	if sha != "" {
+		result, err := r.client.EvalSha(ctx, sha, keys, argv...).Result()
+		if err == nil {
+			return toResultSlice(result)
+		}
+		if !errors.Is(err, redis.Nil) && !isNoScriptErr(err) {
+			return nil, err
+		}
+	}
+
+	result, err := r.client.Eval(ctx, script, keys, argv...).Result()
+	if err != nil {
+		return nil, err
+	}
+	return toResultSlice(result)
+}
+
+// loadScript returns the cached SHA1 for script, loading it into Redis via
+// SCRIPT LOAD the first time it is needed.
+func (r *RedisBackend) loadScript(ctx context.Context, script string) string {
+	r.scriptSHAsMu.Lock()
+	defer r.scriptSHAsMu.Unlock()
+
+	if sha, ok := r.scriptSHAs[script]; ok {
+		return sha
+	}
+
+	sha, err := r.client.ScriptLoad(ctx, script).Result()
+	if err != nil {
+		log.Printf("Failed to load Lua script: %v", err)
+		return ""
+	}
+	r.scriptSHAs[script] = sha
+	return sha
+}
+
+func isNoScriptErr(err error) bool {
+	return err != nil && len(err.Error()) >= 8 && err.Error()[:8] == "NOSCRIPT"
+}
+
+func toResultSlice(result interface{}) ([]interface{}, error) {
+	slice, ok := result.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected script result type: %T", result)
+	}
+	return slice, nil
+}
+
+// crc16Table is the CCITT polynomial table Redis Cluster uses for CRC16.
+var crc16Table = func() [256]uint16 {
+	const poly = 0x1021
+	var table [256]uint16
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+const clusterSlotCount = 16384
+
+// clusterKeySlot computes the Redis Cluster hash slot for key, honoring
+// "{hashtag}" semantics: if key contains a `{...}` substring, only the
+// content between the braces is hashed so related keys can be colocated.
+func clusterKeySlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end >= 0 && end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc = (crc << 8) ^ crc16Table[byte(crc>>8)^key[i]]
+	}
+	return crc % clusterSlotCount
+}
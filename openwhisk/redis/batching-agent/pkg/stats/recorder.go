@@ -0,0 +1,129 @@
+// Package stats provides a bounded-memory latency histogram for computing
+// percentiles over very large sample counts without keeping every raw
+// sample around to sort.
+package stats
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// subBucketsPerMagnitude and magnitudeCount size the log-linear histogram:
+// each power-of-two magnitude band of microseconds [2^m, 2^(m+1)) is divided
+// into subBucketsPerMagnitude equal-width linear buckets, giving ~3% relative
+// error at any scale while using a fixed amount of memory. magnitudeCount=32
+// covers latencies up to roughly 71 minutes, far past anything this agent
+// should ever see.
+const (
+	subBucketsPerMagnitude = 32
+	magnitudeCount         = 32
+	bucketCount            = magnitudeCount * subBucketsPerMagnitude
+)
+
+// Recorder is a concurrency-safe latency histogram. Unlike sorting a
+// []time.Duration of every sample, Record/Snapshot cost is independent of
+// how many samples have been recorded, so millions of requests can be
+// aggregated with a fixed, small footprint.
+type Recorder struct {
+	mu      sync.Mutex
+	buckets [bucketCount]int64
+	count   int64
+	sum     time.Duration
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record adds one latency sample.
+func (r *Recorder) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	idx := bucketIndex(d)
+
+	r.mu.Lock()
+	r.buckets[idx]++
+	r.count++
+	r.sum += d
+	r.mu.Unlock()
+}
+
+// bucketIndex maps d to its log-linear bucket.
+func bucketIndex(d time.Duration) int {
+	us := uint64(d.Microseconds()) + 1 // +1 so a 0us sample lands in magnitude 0, not -Inf
+	magnitude := bits.Len64(us) - 1
+	if magnitude >= magnitudeCount {
+		magnitude = magnitudeCount - 1
+	}
+
+	bandStart := uint64(1) << uint(magnitude)
+	bandWidth := bandStart // band is [bandStart, 2*bandStart)
+	offset := (us - bandStart) * subBucketsPerMagnitude / bandWidth
+	if offset >= subBucketsPerMagnitude {
+		offset = subBucketsPerMagnitude - 1
+	}
+	return magnitude*subBucketsPerMagnitude + int(offset)
+}
+
+// bucketUpperBound returns the upper-bound latency (exclusive) a bucket
+// represents, used as that bucket's value when estimating a percentile.
+func bucketUpperBound(idx int) time.Duration {
+	magnitude := idx / subBucketsPerMagnitude
+	offset := idx % subBucketsPerMagnitude
+
+	bandStart := uint64(1) << uint(magnitude)
+	bandWidth := bandStart
+	us := bandStart + (bandWidth*uint64(offset+1))/subBucketsPerMagnitude
+	return time.Duration(us) * time.Microsecond
+}
+
+// Snapshot is a point-in-time read of a Recorder's percentiles, safe to hold
+// onto and format (e.g. into CSV) after the Recorder keeps recording.
+type Snapshot struct {
+	Count int64
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	P999  time.Duration
+}
+
+// Snapshot computes the current percentiles by walking the bucket counts in
+// order - O(bucketCount), regardless of how many samples were recorded.
+func (r *Recorder) Snapshot() Snapshot {
+	r.mu.Lock()
+	buckets := r.buckets
+	count := r.count
+	sum := r.sum
+	r.mu.Unlock()
+
+	if count == 0 {
+		return Snapshot{}
+	}
+
+	return Snapshot{
+		Count: count,
+		Mean:  sum / time.Duration(count),
+		P50:   percentile(buckets[:], count, 50),
+		P90:   percentile(buckets[:], count, 90),
+		P99:   percentile(buckets[:], count, 99),
+		P999:  percentile(buckets[:], count, 99.9),
+	}
+}
+
+// percentile returns the smallest bucket's upper bound such that at least
+// pct percent of count samples fall at or below it.
+func percentile(buckets []int64, count int64, pct float64) time.Duration {
+	target := int64(float64(count) * pct / 100)
+	var cumulative int64
+	for i, c := range buckets {
+		cumulative += c
+		if cumulative > target {
+			return bucketUpperBound(i)
+		}
+	}
+	return bucketUpperBound(len(buckets) - 1)
+}
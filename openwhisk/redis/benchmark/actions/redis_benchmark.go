@@ -6,14 +6,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
-	"crypto/tls"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/redis/rueidis"
+	"github.com/serverless-benchmarks/redis-benchmark/k8sdiscovery"
 )
 
 // Global variables for node IP caching
@@ -29,11 +33,60 @@ type Configuration struct {
 	UseBatching    bool   `json:"use_batching"`
 	BatchingHost   string `json:"batching_host"`
 	BatchingPort   string `json:"batching_port"`
+
+	// BatchingAgentDaemonSetSelector, if set, discovers the batching agent by
+	// finding the DaemonSet pod matching this label selector on this node
+	// via k8sdiscovery, instead of connecting to BatchingHost:BatchingPort
+	// (or the auto-detected node IP) on a fixed port.
+	BatchingAgentDaemonSetSelector string `json:"batching_agent_daemonset_selector"`
 	RedisHost      string `json:"redis_host"`
 	RedisPort      string `json:"redis_port"`
 	RedisPassword  string `json:"redis_password"`
 	KeyPrefix      string `json:"key_prefix"`
 	ParallelCalls  int    `json:"parallel_calls"`
+
+	// RedisMode selects the topology directRedisOperation connects to:
+	// "single" (default), "cluster", or "sentinel".
+	RedisMode          string   `json:"redis_mode"`
+	RedisClusterAddrs  []string `json:"redis_cluster_addrs"`
+	SentinelMasterName string   `json:"sentinel_master_name"`
+	SentinelAddrs      []string `json:"sentinel_addrs"`
+
+	// OperationMode selects how each worker issues its share of ops:
+	// "individual" (default, one RTT per op), "pipeline" (batched with
+	// redis.Pipeliner, using MGET/MSET/DEL-multi fast paths where possible),
+	// or "transaction" (batched with TxPipeline/MULTI-EXEC).
+	OperationMode string `json:"operation_mode"`
+	PipelineSize  int    `json:"pipeline_size"`
+
+	// ValueSize pads generated values out to this many bytes (get/set/hset/
+	// geoadd members). 0 keeps the existing short "value_N" strings.
+	ValueSize int `json:"value_size"`
+	// ListLength is how many elements lpush pushes per op and lrange reads
+	// back per op.
+	ListLength int `json:"list_length"`
+	// StreamConsumerGroup, if set, makes xread use XReadGroup against this
+	// group (created with MKSTREAM/'$' on first use) instead of a plain
+	// XRead from the start of the stream.
+	StreamConsumerGroup string `json:"stream_consumer_group"`
+	// GeoRadius is the search radius, in kilometers, georadius queries use.
+	GeoRadius float64 `json:"geo_radius"`
+
+	// ClientLibrary selects the RedisBackend get/set/del/exists run through in
+	// "individual" OperationMode: "goredis" (default) or "rueidis". Pipeline/
+	// transaction modes and the data-type ops above always use go-redis,
+	// since they depend on its Pipeliner/UniversalClient surface.
+	ClientLibrary string `json:"client_library"`
+	// ClientSideCacheTTLMs, when > 0 and ClientLibrary is "rueidis", routes
+	// GET through rueidis's RESP3 client-side cache (DoCache) with this TTL
+	// instead of a plain Do.
+	ClientSideCacheTTLMs int `json:"client_side_cache_ttl_ms"`
+
+	// ReportMode controls how much of Results comes back in the response:
+	// "full" (default) returns every OperationResult, "summary" returns none
+	// and only Response.Stats, "histogram" returns Stats plus a small sample
+	// of Results (up to resultsSampleCap).
+	ReportMode string `json:"report_mode"`
 }
 
 // Response represents the benchmark results
@@ -48,6 +101,10 @@ type Response struct {
 	RedisHost      string            `json:"redis_host,omitempty"`
 	SuccessCount   int               `json:"success_count"`
 	Results        []OperationResult `json:"results"`
+	// Stats is populated in "summary"/"histogram" ReportMode with aggregated
+	// latency percentiles and throughput, so callers don't need every raw
+	// OperationResult to compute them.
+	Stats          *Stats            `json:"stats,omitempty"`
 	Error          string            `json:"error,omitempty"`
 }
 
@@ -58,6 +115,195 @@ type OperationResult struct {
 	Value         string  `json:"value,omitempty"`
 	Error         string  `json:"error,omitempty"`
 	DurationMs    float64 `json:"duration_ms"`
+	Shard         string  `json:"shard,omitempty"` // which cluster slot/node or sentinel master served this op
+
+	// BatchDurationMs is the wall-clock time of the pipeline/transaction this
+	// result was executed in, repeated across every result in the batch so
+	// callers can compare it against the sum of DurationMs (which is only an
+	// approximation: batch duration spread evenly over the batch's commands).
+	BatchDurationMs float64 `json:"batch_duration_ms,omitempty"`
+
+	// ListLen is the list length lpush/lrange reported: the list's new
+	// length after an lpush, or the number of elements lrange returned.
+	ListLen int `json:"list_len,omitempty"`
+	// StreamID is the entry ID xadd assigned, or the last entry ID xread saw.
+	StreamID string `json:"stream_id,omitempty"`
+	// MatchedCount is the number of members georadius returned.
+	MatchedCount int `json:"matched_count,omitempty"`
+	// CacheHit reports whether a rueidis GET was served from its RESP3
+	// client-side cache rather than a round trip to Redis.
+	CacheHit bool `json:"cache_hit,omitempty"`
+}
+
+// resultsSampleCap bounds how many OperationResults "histogram" ReportMode
+// keeps in Response.Results, so a large benchmark still returns a bounded
+// sample of individual ops alongside the full Stats.
+const resultsSampleCap = 100
+
+// LatencyStats summarizes a set of operation durations (in milliseconds)
+// without retaining every raw sample.
+type LatencyStats struct {
+	Count  int     `json:"count"`
+	P50    float64 `json:"p50_ms"`
+	P90    float64 `json:"p90_ms"`
+	P95    float64 `json:"p95_ms"`
+	P99    float64 `json:"p99_ms"`
+	P999   float64 `json:"p999_ms"`
+	Min    float64 `json:"min_ms"`
+	Max    float64 `json:"max_ms"`
+	Mean   float64 `json:"mean_ms"`
+	StdDev float64 `json:"stddev_ms"`
+}
+
+// HistogramBucket is one logarithmic latency bucket: Count operations fell
+// in [LowerBoundMs, LowerBoundMs*1.1).
+type HistogramBucket struct {
+	LowerBoundMs float64 `json:"lower_bound_ms"`
+	Count        int     `json:"count"`
+}
+
+// Stats is the aggregated view of a benchmark run's op durations, emitted in
+// Response.Stats for "summary"/"histogram" ReportMode.
+type Stats struct {
+	LatencyStats
+	ThroughputOpsSec float64                 `json:"throughput_ops_sec"`
+	PerShard         map[string]LatencyStats `json:"per_shard,omitempty"`
+	// Buckets is only populated in "histogram" ReportMode.
+	Buckets []HistogramBucket `json:"buckets,omitempty"`
+}
+
+// histogramLogBase is the bucket growth factor: each bucket covers durations
+// within 10% of the previous bucket's lower bound, which keeps relative
+// error on any reported percentile at roughly that 10%.
+const histogramLogBase = 1.1
+
+// latencyHistogram is an HDR-style logarithmic-bucketed histogram of
+// operation durations, used to estimate percentiles without storing every
+// raw sample.
+type latencyHistogram struct {
+	count   int
+	sum     float64
+	sumSq   float64
+	min     float64
+	max     float64
+	buckets map[int]int
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{buckets: make(map[int]int)}
+}
+
+// record adds a duration (in milliseconds) to the histogram.
+func (h *latencyHistogram) record(ms float64) {
+	if ms < 0 {
+		ms = 0
+	}
+	if h.count == 0 {
+		h.min, h.max = ms, ms
+	} else {
+		if ms < h.min {
+			h.min = ms
+		}
+		if ms > h.max {
+			h.max = ms
+		}
+	}
+	h.count++
+	h.sum += ms
+	h.sumSq += ms * ms
+	h.buckets[bucketIndex(ms)]++
+}
+
+// bucketIndex maps a duration onto its logarithmic bucket index, treating
+// non-positive durations as falling into bucket 0 (sub-millisecond ops).
+func bucketIndex(ms float64) int {
+	if ms <= 0 {
+		return 0
+	}
+	return int(math.Floor(math.Log(ms) / math.Log(histogramLogBase)))
+}
+
+// bucketLowerBound returns the duration a bucket index's lower edge represents.
+func bucketLowerBound(idx int) float64 {
+	return math.Pow(histogramLogBase, float64(idx))
+}
+
+// percentile estimates the p-th percentile (0-100) duration by walking
+// buckets in increasing order until the cumulative count reaches the target
+// rank, and returning that bucket's lower bound as the estimate.
+func (h *latencyHistogram) percentile(p float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	indices := make([]int, 0, len(h.buckets))
+	for idx := range h.buckets {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	target := int(math.Ceil(p / 100 * float64(h.count)))
+	if target < 1 {
+		target = 1
+	}
+	cumulative := 0
+	for _, idx := range indices {
+		cumulative += h.buckets[idx]
+		if cumulative >= target {
+			return bucketLowerBound(idx)
+		}
+	}
+	return h.max
+}
+
+func (h *latencyHistogram) mean() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / float64(h.count)
+}
+
+func (h *latencyHistogram) stddev() float64 {
+	if h.count == 0 {
+		return 0
+	}
+	mean := h.mean()
+	variance := h.sumSq/float64(h.count) - mean*mean
+	if variance < 0 {
+		variance = 0
+	}
+	return math.Sqrt(variance)
+}
+
+// latencyStats converts the histogram into a LatencyStats snapshot.
+func (h *latencyHistogram) latencyStats() LatencyStats {
+	return LatencyStats{
+		Count:  h.count,
+		P50:    h.percentile(50),
+		P90:    h.percentile(90),
+		P95:    h.percentile(95),
+		P99:    h.percentile(99),
+		P999:   h.percentile(99.9),
+		Min:    h.min,
+		Max:    h.max,
+		Mean:   h.mean(),
+		StdDev: h.stddev(),
+	}
+}
+
+// histogramBuckets returns the non-empty buckets in increasing order, for
+// Stats.Buckets in "histogram" ReportMode.
+func (h *latencyHistogram) histogramBuckets() []HistogramBucket {
+	indices := make([]int, 0, len(h.buckets))
+	for idx := range h.buckets {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	out := make([]HistogramBucket, 0, len(indices))
+	for _, idx := range indices {
+		out = append(out, HistogramBucket{LowerBoundMs: bucketLowerBound(idx), Count: h.buckets[idx]})
+	}
+	return out
 }
 
 // getNodeIP retrieves and caches the node IP for the current pod
@@ -71,17 +317,17 @@ func getNodeIP() (string, error) {
 			cachedNodeIP = batchingHost
 			return
 		}
-		
-		// Then try to get the node IP using the Kubernetes API
+
+		// Then try to get the node IP via the in-cluster Kubernetes API
 		var ip string
-		ip, err = fetchNodeIPFromKubernetesAPI()
+		ip, err = discovererNodeIP()
 		if err == nil && ip != "" {
 			log.Printf("Successfully retrieved node IP from Kubernetes API: %s", ip)
 			cachedNodeIP = ip
 			return
 		}
 		log.Printf("Failed to get node IP from Kubernetes API: %v, trying fallbacks", err)
-		
+
 		// Fallback: check for environment variables
 		ip = os.Getenv("NODE_IP")
 		if ip != "" {
@@ -89,7 +335,7 @@ func getNodeIP() (string, error) {
 			cachedNodeIP = ip
 			return
 		}
-		
+
 		// Try other common environment variables
 		for _, envVar := range []string{"KUBERNETES_NODE_IP", "HOST_IP", "HOSTNAME"} {
 			ip = os.Getenv(envVar)
@@ -99,111 +345,72 @@ func getNodeIP() (string, error) {
 				return
 			}
 		}
-		
+
 		// Final fallback: use a default hostname for the node
 		cachedNodeIP = "localhost"
 		log.Printf("No node IP could be determined. Using default: %s", cachedNodeIP)
 	})
-	
+
 	if cachedNodeIP == "" {
 		return "", fmt.Errorf("failed to determine node IP")
 	}
-	
+
 	return cachedNodeIP, err
 }
 
-// fetchNodeIPFromKubernetesAPI retrieves the node IP using the Kubernetes API
-func fetchNodeIPFromKubernetesAPI() (string, error) {
-	// Get pod name from hostname
-	hostname, err := os.Hostname()
-	if err != nil {
-		return "", fmt.Errorf("failed to get hostname: %v", err)
-	}
-	
-	// Check if service account token exists
-	tokenFile := "/var/run/secrets/kubernetes.io/serviceaccount/token"
-	if _, err := os.Stat(tokenFile); os.IsNotExist(err) {
-		return "", fmt.Errorf("service account token not found")
-	}
-	
-	// Read the service account token
-	token, err := ioutil.ReadFile(tokenFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to read service account token: %v", err)
-	}
-	
-	// Get Kubernetes API server address
-	kubeHost := os.Getenv("KUBERNETES_SERVICE_HOST")
-	kubePort := os.Getenv("KUBERNETES_SERVICE_PORT")
-	if kubeHost == "" || kubePort == "" {
-		return "", fmt.Errorf("Kubernetes service host or port not found")
-	}
-	
-	// Read namespace
-	namespaceFile := "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
-	namespace, err := ioutil.ReadFile(namespaceFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to read namespace: %v", err)
-	}
-	
-	// Create request to Kubernetes API
-	url := fmt.Sprintf("https://%s:%s/api/v1/namespaces/%s/pods/%s", 
-		kubeHost, kubePort, string(namespace), hostname)
-	
-	req, err := http.NewRequest("GET", url, nil)
+var (
+	discoverer     *k8sdiscovery.Discoverer
+	discovererOnce sync.Once
+	discovererErr  error
+
+	cachedAgentEndpoint *k8sdiscovery.AgentEndpoint
+	agentEndpointMu     sync.Mutex
+)
+
+// getDiscoverer lazily builds the process-wide k8sdiscovery.Discoverer.
+func getDiscoverer() (*k8sdiscovery.Discoverer, error) {
+	discovererOnce.Do(func() {
+		discoverer, discovererErr = k8sdiscovery.NewInClusterDiscoverer()
+	})
+	return discoverer, discovererErr
+}
+
+// discovererNodeIP fetches this pod's node IP through k8sdiscovery, in-cluster
+// config and all, instead of the old hand-rolled bearer-token HTTPS call.
+func discovererNodeIP() (string, error) {
+	d, err := getDiscoverer()
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return "", err
 	}
-	req.Header.Set("Authorization", "Bearer "+string(token))
-	
-	// Configure TLS to skip verification
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	return d.NodeIP(context.Background())
+}
+
+// getAgentEndpoint resolves the batching-agent pod on this node via the
+// DaemonSet's label selector, caching the result alongside cachedNodeIP.
+// Pass forceRefresh on a connection failure to discard the cached endpoint
+// and look up the (likely rescheduled) agent pod again.
+func getAgentEndpoint(labelSelector string, forceRefresh bool) (*k8sdiscovery.AgentEndpoint, error) {
+	agentEndpointMu.Lock()
+	defer agentEndpointMu.Unlock()
+
+	if cachedAgentEndpoint != nil && !forceRefresh {
+		return cachedAgentEndpoint, nil
 	}
-	client := &http.Client{Transport: tr}
-	
-	log.Printf("Attempting to query Kubernetes API at: %s", url)
-	
-	// Send request
-	resp, err := client.Do(req)
+
+	d, err := getDiscoverer()
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to get pod info: status %s, body: %s", resp.Status, string(bodyBytes))
-	}
-	
-	// Parse response
-	body, err := ioutil.ReadAll(resp.Body)
+	endpoint, err := d.FindAgentOnNode(context.Background(), labelSelector)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
-	}
-	
-	// Parse JSON
-	var podInfo map[string]interface{}
-	if err := json.Unmarshal(body, &podInfo); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+		return nil, err
 	}
-	
-	// Extract hostIP from status
-	status, ok := podInfo["status"].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("status not found in pod info")
-	}
-	
-	hostIP, ok := status["hostIP"].(string)
-	if !ok {
-		return "", fmt.Errorf("hostIP not found in pod status")
-	}
-	
-	return hostIP, nil
+	cachedAgentEndpoint = endpoint
+	return endpoint, nil
 }
 
 // directRedisOperation performs a Redis operation directly
-func directRedisOperation(ctx context.Context, redisClient *redis.Client, opType, key, value string) (string, error) {
+func directRedisOperation(ctx context.Context, redisClient redis.UniversalClient, opType, key, value string) (string, error) {
 	switch opType {
 	case "get":
 		return redisClient.Get(ctx, key).Result()
@@ -220,6 +427,416 @@ func directRedisOperation(ctx context.Context, redisClient *redis.Client, opType
 	}
 }
 
+// RedisBackend abstracts the get/set/del/exists path so runBenchmark can
+// swap go-redis for rueidis without the worker loop caring which client
+// library actually issued the command.
+type RedisBackend interface {
+	// Do runs opType against key/value and reports whether the result came
+	// from a client-side cache rather than a round trip to Redis.
+	Do(ctx context.Context, opType, key, value string) (val string, cacheHit bool, err error)
+	Close() error
+}
+
+// goRedisBackend is the default RedisBackend, delegating to the existing
+// go-redis/v9 client and directRedisOperation. It never reports a cache hit:
+// go-redis/v9 has no client-side caching support.
+type goRedisBackend struct {
+	client redis.UniversalClient
+}
+
+func (b *goRedisBackend) Do(ctx context.Context, opType, key, value string) (string, bool, error) {
+	val, err := directRedisOperation(ctx, b.client, opType, key, value)
+	return val, false, err
+}
+
+func (b *goRedisBackend) Close() error {
+	return b.client.Close()
+}
+
+// rueidisBackend runs get/set/del/exists over rueidis's RESP3 client, which
+// auto-pipelines commands under the hood. When cacheTTL > 0, GET is issued
+// through DoCache so repeat reads of the same key can be served from
+// rueidis's in-memory tracking cache instead of hitting Redis.
+type rueidisBackend struct {
+	client   rueidis.Client
+	cacheTTL time.Duration
+}
+
+func (b *rueidisBackend) Do(ctx context.Context, opType, key, value string) (string, bool, error) {
+	switch opType {
+	case "get":
+		if b.cacheTTL > 0 {
+			resp := b.client.DoCache(ctx, b.client.B().Get().Key(key).Cache(), b.cacheTTL)
+			val, err := resp.ToString()
+			return val, resp.IsCacheHit(), normalizeRueidisNil(err)
+		}
+		val, err := b.client.Do(ctx, b.client.B().Get().Key(key).Build()).ToString()
+		return val, false, normalizeRueidisNil(err)
+	case "set":
+		val, err := b.client.Do(ctx, b.client.B().Set().Key(key).Value(value).Build()).ToString()
+		return val, false, err
+	case "del":
+		n, err := b.client.Do(ctx, b.client.B().Del().Key(key).Build()).ToInt64()
+		return strconv.FormatInt(n, 10), false, err
+	case "exists":
+		n, err := b.client.Do(ctx, b.client.B().Exists().Key(key).Build()).ToInt64()
+		return strconv.FormatInt(n, 10), false, err
+	default:
+		return "", false, fmt.Errorf("unsupported operation type: %s", opType)
+	}
+}
+
+func (b *rueidisBackend) Close() error {
+	b.client.Close()
+	return nil
+}
+
+// normalizeRueidisNil maps rueidis's own nil-reply error to redis.Nil so
+// callers can use one sentinel regardless of which RedisBackend is active.
+func normalizeRueidisNil(err error) error {
+	if rueidis.IsRedisNil(err) {
+		return redis.Nil
+	}
+	return err
+}
+
+// dataTypeOps are the OperationTypes handled by dataTypeRedisOperation rather
+// than directRedisOperation/batchedRedisOperation's string-key GET/SET/DEL/
+// EXISTS.
+var dataTypeOps = map[string]bool{
+	"lpush": true, "lrange": true,
+	"hset": true, "hgetall": true,
+	"xadd": true, "xread": true,
+	"geoadd": true, "georadius": true,
+	"publish": true, "subscribe": true,
+}
+
+// dataTypeRedisOperation covers the non-string-key workload matrix: lists
+// (lpush/lrange), hashes (hset/hgetall), streams (xadd/xread), geo sets
+// (geoadd/georadius), and pub/sub (publish/subscribe). It's a separate
+// function from directRedisOperation because these ops return structured
+// data (list length, stream ID, match count) that a single string result
+// can't carry, and several of them need config knobs (ListLength, GeoRadius,
+// StreamConsumerGroup) directRedisOperation's callers don't have. There is
+// no batching-agent equivalent: the agent's HTTP surface only proxies GET/
+// SET/DEL/EXISTS, so these op types only run against direct Redis access.
+func dataTypeRedisOperation(ctx context.Context, redisClient redis.UniversalClient, config Configuration, key, value string) (OperationResult, error) {
+	switch config.OperationType {
+	case "lpush":
+		n, err := redisClient.LPush(ctx, key, value).Result()
+		return OperationResult{ListLen: int(n)}, err
+
+	case "lrange":
+		count := int64(config.ListLength)
+		if count <= 0 {
+			count = 10
+		}
+		vals, err := redisClient.LRange(ctx, key, 0, count-1).Result()
+		result := OperationResult{ListLen: len(vals)}
+		if len(vals) > 0 {
+			result.Value = vals[0]
+		}
+		return result, err
+
+	case "hset":
+		_, err := redisClient.HSet(ctx, key, "value", value).Result()
+		return OperationResult{Value: value}, err
+
+	case "hgetall":
+		fields, err := redisClient.HGetAll(ctx, key).Result()
+		return OperationResult{Value: fmt.Sprintf("%v", fields)}, err
+
+	case "xadd":
+		id, err := redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			ID:     "*",
+			Values: []string{"value", value},
+		}).Result()
+		return OperationResult{StreamID: id}, err
+
+	case "xread":
+		var streams []redis.XStream
+		var err error
+		if config.StreamConsumerGroup != "" {
+			streams, err = redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group:    config.StreamConsumerGroup,
+				Consumer: key,
+				Streams:  []string{key, ">"},
+				Count:    1,
+			}).Result()
+		} else {
+			streams, err = redisClient.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{key, "0"},
+				Count:   1,
+			}).Result()
+		}
+		result := OperationResult{}
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				result.StreamID = msg.ID
+				if v, ok := msg.Values["value"].(string); ok {
+					result.Value = v
+				}
+			}
+		}
+		return result, err
+
+	case "geoadd":
+		lon, lat := geoCoordsFor(key)
+		n, err := redisClient.GeoAdd(ctx, key, &redis.GeoLocation{Name: value, Longitude: lon, Latitude: lat}).Result()
+		return OperationResult{ListLen: int(n)}, err
+
+	case "georadius":
+		radius := config.GeoRadius
+		if radius <= 0 {
+			radius = 10
+		}
+		lon, lat := geoCoordsFor(key)
+		members, err := redisClient.GeoRadius(ctx, key, lon, lat, &redis.GeoRadiusQuery{
+			Radius: radius,
+			Unit:   "km",
+		}).Result()
+		return OperationResult{MatchedCount: len(members)}, err
+
+	case "publish":
+		subscribers, err := redisClient.Publish(ctx, key, value).Result()
+		return OperationResult{Value: strconv.FormatInt(subscribers, 10)}, err
+
+	case "subscribe":
+		pubsub := redisClient.Subscribe(ctx, key)
+		defer pubsub.Close()
+		recvCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		msg, err := pubsub.ReceiveMessage(recvCtx)
+		if err != nil {
+			return OperationResult{}, err
+		}
+		return OperationResult{Value: msg.Payload}, nil
+
+	default:
+		return OperationResult{}, fmt.Errorf("unsupported operation type: %s", config.OperationType)
+	}
+}
+
+// genValue builds the value for op i, padding it out to size bytes when size
+// is larger than the base "value_N" string. size <= 0 keeps the existing
+// short value.
+func genValue(i, size int) string {
+	base := fmt.Sprintf("value_%d", i)
+	if size <= len(base) {
+		return base
+	}
+	return base + strings.Repeat(".", size-len(base))
+}
+
+// geoCoordsFor derives a deterministic, spread-out (longitude, latitude)
+// pair from key so repeated benchmark runs geoadd the same member to the
+// same place, using the same CRC16 hash clusterKeySlot relies on.
+func geoCoordsFor(key string) (float64, float64) {
+	slot := clusterKeySlot(key)
+	lon := float64(slot)/16384.0*360.0 - 180.0
+	lat := float64(slot%180)/180.0*170.0 - 85.0
+	return lon, lat
+}
+
+// shardFor reports which shard or master actually serves key, so a benchmark
+// run can tell hot-shard workloads from balanced ones in its per-op results.
+// It's resolved locally from the client's topology rather than queried from
+// Redis, since all we need is a label for the report, not the routing
+// decision itself - go-redis's UniversalClient already does the real
+// routing internally.
+func shardFor(redisClient redis.UniversalClient, mode, key string) string {
+	switch mode {
+	case "cluster":
+		return fmt.Sprintf("slot:%d", clusterKeySlot(key))
+	case "sentinel":
+		return "master"
+	default:
+		return "single"
+	}
+}
+
+// clusterKeySlot computes the Redis Cluster hash slot for key, honoring
+// "{hashtag}" semantics: if key contains a `{...}` substring, only the
+// content between the braces is hashed so related keys can be colocated.
+func clusterKeySlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end >= 0 && end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc = (crc << 8) ^ crc16Table[byte(crc>>8)^key[i]]
+	}
+	return crc % 16384
+}
+
+// crc16Table is the CCITT polynomial table Redis Cluster uses for CRC16.
+var crc16Table = func() [256]uint16 {
+	const poly = 0x1021
+	var table [256]uint16
+	for i := 0; i < 256; i++ {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// pipelinedRedisOperation runs opType against keys/values as a single
+// redis.Pipeliner or TxPipeline batch (mode "pipeline" or "transaction"),
+// collapsing `len(keys)` round trips into one. For "pipeline" mode on
+// get/set/del it uses the MGET/MSET/DEL multi-key fast path instead of
+// queuing one command per key, since those still save a command each on top
+// of the round-trip savings pipelining already gives. Per-command latency in
+// the returned results is only an approximation (the batch's wall-clock time
+// split evenly); BatchDurationMs on each result carries the real figure.
+func pipelinedRedisOperation(ctx context.Context, redisClient redis.UniversalClient, mode, opType string, keys, values []string) ([]OperationResult, error) {
+	results := make([]OperationResult, len(keys))
+	for i, key := range keys {
+		results[i].Key = key
+		results[i].Shard = shardFor(redisClient, mode, key)
+	}
+
+	var pipe redis.Pipeliner
+	if mode == "transaction" {
+		pipe = redisClient.TxPipeline()
+	} else {
+		pipe = redisClient.Pipeline()
+	}
+
+	batchStart := time.Now()
+
+	if mode == "pipeline" && (opType == "get" || opType == "set" || opType == "del") {
+		switch opType {
+		case "get":
+			c := pipe.MGet(ctx, keys...)
+			if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+				return nil, fmt.Errorf("pipeline exec failed: %v", err)
+			}
+			vals, err := c.Result()
+			if err != nil {
+				return nil, fmt.Errorf("mget failed: %v", err)
+			}
+			fillBatchResults(results, batchStart, func(i int) (string, error) {
+				if vals[i] == nil {
+					return "", redis.Nil
+				}
+				return fmt.Sprintf("%v", vals[i]), nil
+			})
+			return results, nil
+		case "set":
+			pairs := make([]interface{}, 0, len(keys)*2)
+			for i, key := range keys {
+				pairs = append(pairs, key, values[i])
+			}
+			c := pipe.MSet(ctx, pairs...)
+			if _, err := pipe.Exec(ctx); err != nil {
+				return nil, fmt.Errorf("pipeline exec failed: %v", err)
+			}
+			res, err := c.Result()
+			fillBatchResults(results, batchStart, func(int) (string, error) { return res, err })
+			return results, nil
+		case "del":
+			c := pipe.Del(ctx, keys...)
+			if _, err := pipe.Exec(ctx); err != nil {
+				return nil, fmt.Errorf("pipeline exec failed: %v", err)
+			}
+			deleted, err := c.Result()
+			fillBatchResults(results, batchStart, func(int) (string, error) { return strconv.FormatInt(deleted, 10), err })
+			return results, nil
+		}
+	}
+
+	// Transaction mode, or an op type with no multi-key fast path: queue one
+	// command per key and read back each result individually.
+	cmders := make([]redis.Cmder, len(keys))
+	for i, key := range keys {
+		switch opType {
+		case "get":
+			cmders[i] = pipe.Get(ctx, key)
+		case "set":
+			cmders[i] = pipe.Set(ctx, key, values[i], 0)
+		case "del":
+			cmders[i] = pipe.Del(ctx, key)
+		case "exists":
+			cmders[i] = pipe.Exists(ctx, key)
+		default:
+			return nil, fmt.Errorf("unsupported operation type: %s", opType)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("pipeline exec failed: %v", err)
+	}
+
+	fillBatchResults(results, batchStart, func(i int) (string, error) {
+		switch c := cmders[i].(type) {
+		case *redis.StringCmd:
+			return c.Result()
+		case *redis.StatusCmd:
+			return c.Result()
+		case *redis.IntCmd:
+			n, err := c.Result()
+			return strconv.FormatInt(n, 10), err
+		default:
+			return "", fmt.Errorf("unsupported operation type: %s", opType)
+		}
+	})
+
+	return results, nil
+}
+
+// fillBatchResults stamps DurationMs/BatchDurationMs/Status/Value/Error onto
+// every result in a batch, given a per-index value/error getter.
+func fillBatchResults(results []OperationResult, batchStart time.Time, get func(i int) (string, error)) {
+	batchDuration := time.Since(batchStart)
+	batchMs := float64(batchDuration) / float64(time.Millisecond)
+	perCmdMs := batchMs / float64(len(results))
+
+	for i := range results {
+		val, err := get(i)
+		results[i].DurationMs = perCmdMs
+		results[i].BatchDurationMs = batchMs
+		if err != nil && err != redis.Nil {
+			results[i].Status = "error"
+			results[i].Error = err.Error()
+		} else {
+			results[i].Status = "success"
+			if err != redis.Nil {
+				results[i].Value = val
+			}
+		}
+	}
+}
+
+// batchingAgentHealthy pings the batching agent's /health endpoint and logs
+// the outcome, returning whether it responded with 200 OK.
+func batchingAgentHealthy(batchingURL string) bool {
+	testURL := fmt.Sprintf("%s/health", batchingURL)
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(testURL)
+	if err != nil {
+		log.Printf("Warning: Failed to connect to batching agent health endpoint: %v", err)
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Warning: Batching agent health check returned non-OK status: %d", resp.StatusCode)
+		return false
+	}
+	log.Printf("Successfully connected to batching agent at %s", batchingURL)
+	return true
+}
+
 // batchedRedisOperation performs a Redis operation through the batching agent
 func batchedRedisOperation(batchingURL, opType, key, value string) (string, error) {
 	var url string
@@ -311,73 +928,184 @@ func runBenchmark(config Configuration) Response {
 	if config.KeyPrefix == "" {
 		config.KeyPrefix = "test_key"
 	}
-	
+	if config.OperationMode == "" {
+		config.OperationMode = "individual"
+	}
+	if config.PipelineSize <= 0 {
+		config.PipelineSize = 50
+	}
+
+	if config.ClientLibrary == "" {
+		config.ClientLibrary = "goredis"
+	}
+	if config.ReportMode == "" {
+		config.ReportMode = "full"
+	}
+
 	// Set up for direct Redis access
-	var redisClient *redis.Client
+	var redisClient redis.UniversalClient
+	var backend RedisBackend
 	var batchingURL string
-	
+
 	if config.UseBatching {
-		// Use batching agent
-		batchingHost := config.BatchingHost
-		batchingPort := config.BatchingPort
-		
-		// If host not provided, detect node IP
-		if batchingHost == "" {
-			log.Printf("No batching agent host provided, attempting to auto-detect")
-			var err error
-			batchingHost, err = getNodeIP()
+		if config.BatchingAgentDaemonSetSelector != "" {
+			// Discover the batching-agent pod on this node via its DaemonSet
+			// label selector rather than a fixed port on the node IP.
+			endpoint, err := getAgentEndpoint(config.BatchingAgentDaemonSetSelector, false)
 			if err != nil {
 				response.StatusCode = 500
-				response.Error = fmt.Sprintf("Failed to get node IP: %v", err)
+				response.Error = fmt.Sprintf("Failed to discover batching agent: %v", err)
 				return response
 			}
-			log.Printf("Auto-detected batching agent host: %s", batchingHost)
-		} else {
-			log.Printf("Using provided batching agent host: %s", batchingHost)
-		}
-		
-		// Use default port if not provided
-		if batchingPort == "" {
-			batchingPort = "8080"
-		}
-		
-		batchingURL = fmt.Sprintf("http://%s:%s", batchingHost, batchingPort)
-		response.BatchingURL = batchingURL
-		log.Printf("Using Redis batching agent at %s", batchingURL)
-		
-		// Test the connection to the batching agent
-		testURL := fmt.Sprintf("%s/health", batchingURL)
-		client := &http.Client{Timeout: 2 * time.Second}
-		resp, err := client.Get(testURL)
-		if err != nil {
-			log.Printf("Warning: Failed to connect to batching agent health endpoint: %v", err)
+			batchingURL = fmt.Sprintf("http://%s:%d", endpoint.PodIP, endpoint.Port)
+			response.BatchingURL = batchingURL
+			log.Printf("Discovered batching agent pod at %s", batchingURL)
+
+			// Test the connection; on failure, assume the agent was
+			// rescheduled and refresh the cached endpoint once.
+			if !batchingAgentHealthy(batchingURL) {
+				log.Printf("Warning: cached batching agent endpoint %s looks unhealthy, refreshing", batchingURL)
+				endpoint, err = getAgentEndpoint(config.BatchingAgentDaemonSetSelector, true)
+				if err != nil {
+					response.StatusCode = 500
+					response.Error = fmt.Sprintf("Failed to rediscover batching agent: %v", err)
+					return response
+				}
+				batchingURL = fmt.Sprintf("http://%s:%d", endpoint.PodIP, endpoint.Port)
+				response.BatchingURL = batchingURL
+				batchingAgentHealthy(batchingURL)
+				log.Printf("Failed over to batching agent pod at %s", batchingURL)
+			}
 		} else {
-			resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				log.Printf("Warning: Batching agent health check returned non-OK status: %d", resp.StatusCode)
+			// Use batching agent
+			batchingHost := config.BatchingHost
+			batchingPort := config.BatchingPort
+
+			// If host not provided, detect node IP
+			if batchingHost == "" {
+				log.Printf("No batching agent host provided, attempting to auto-detect")
+				var err error
+				batchingHost, err = getNodeIP()
+				if err != nil {
+					response.StatusCode = 500
+					response.Error = fmt.Sprintf("Failed to get node IP: %v", err)
+					return response
+				}
+				log.Printf("Auto-detected batching agent host: %s", batchingHost)
 			} else {
-				log.Printf("Successfully connected to batching agent at %s", batchingURL)
+				log.Printf("Using provided batching agent host: %s", batchingHost)
+			}
+
+			// Use default port if not provided
+			if batchingPort == "" {
+				batchingPort = "8080"
 			}
+
+			batchingURL = fmt.Sprintf("http://%s:%s", batchingHost, batchingPort)
+			response.BatchingURL = batchingURL
+			log.Printf("Using Redis batching agent at %s", batchingURL)
+
+			batchingAgentHealthy(batchingURL)
 		}
-	} else {
-		// Direct Redis access
-		redisHost := config.RedisHost
-		redisPort := config.RedisPort
-		
-		if redisHost == "" {
-			redisHost = "localhost"
-		}
-		if redisPort == "" {
-			redisPort = "6379"
-		}
-		
-		redisAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
-		redisClient = redis.NewClient(&redis.Options{
-			Addr:     redisAddr,
-			Password: config.RedisPassword,
-			DB:       0,
+	} else if config.ClientLibrary == "rueidis" {
+		// rueidis backend: only the individual get/set/del/exists path is
+		// wired up (see RedisBackend's doc comment) since pipelining and the
+		// data-type ops depend on go-redis/v9's Pipeliner/UniversalClient.
+		if config.OperationMode != "" && config.OperationMode != "individual" {
+			response.StatusCode = 500
+			response.Error = "client_library \"rueidis\" only supports operation_mode \"individual\""
+			return response
+		}
+		if dataTypeOps[config.OperationType] {
+			response.StatusCode = 500
+			response.Error = fmt.Sprintf("client_library \"rueidis\" does not support operation_type %q", config.OperationType)
+			return response
+		}
+		if config.RedisMode == "sentinel" {
+			response.StatusCode = 500
+			response.Error = "client_library \"rueidis\" does not support redis_mode \"sentinel\" yet"
+			return response
+		}
+
+		addrs := config.RedisClusterAddrs
+		if len(addrs) == 0 {
+			redisHost := config.RedisHost
+			if redisHost == "" {
+				redisHost = "localhost"
+			}
+			redisPort := config.RedisPort
+			if redisPort == "" {
+				redisPort = "6379"
+			}
+			addrs = []string{fmt.Sprintf("%s:%s", redisHost, redisPort)}
+		}
+
+		rueidisClient, err := rueidis.NewClient(rueidis.ClientOption{
+			InitAddress: addrs,
+			Password:    config.RedisPassword,
 		})
-		
+		if err != nil {
+			response.StatusCode = 500
+			response.Error = fmt.Sprintf("Failed to create rueidis client: %v", err)
+			return response
+		}
+
+		cacheTTL := time.Duration(config.ClientSideCacheTTLMs) * time.Millisecond
+		backend = &rueidisBackend{client: rueidisClient, cacheTTL: cacheTTL}
+		response.RedisHost = strings.Join(addrs, ",")
+		log.Printf("Connected to Redis via rueidis at %v (cache TTL: %v)", addrs, cacheTTL)
+	} else {
+		// Direct Redis access, against a single node, a Sentinel-managed HA
+		// deployment, or a Cluster, depending on RedisMode.
+		switch config.RedisMode {
+		case "cluster":
+			if len(config.RedisClusterAddrs) == 0 {
+				response.StatusCode = 500
+				response.Error = "redis_cluster_addrs is required when redis_mode is \"cluster\""
+				return response
+			}
+			redisClient = redis.NewClusterClient(&redis.ClusterOptions{
+				Addrs:    config.RedisClusterAddrs,
+				Password: config.RedisPassword,
+			})
+			response.RedisHost = strings.Join(config.RedisClusterAddrs, ",")
+			log.Printf("Connecting to Redis Cluster at %v", config.RedisClusterAddrs)
+
+		case "sentinel":
+			if len(config.SentinelAddrs) == 0 || config.SentinelMasterName == "" {
+				response.StatusCode = 500
+				response.Error = "sentinel_addrs and sentinel_master_name are required when redis_mode is \"sentinel\""
+				return response
+			}
+			redisClient = redis.NewFailoverClient(&redis.FailoverOptions{
+				SentinelAddrs: config.SentinelAddrs,
+				MasterName:    config.SentinelMasterName,
+				Password:      config.RedisPassword,
+			})
+			response.RedisHost = config.SentinelMasterName
+			log.Printf("Connecting to Redis Sentinel master %q via %v", config.SentinelMasterName, config.SentinelAddrs)
+
+		default:
+			redisHost := config.RedisHost
+			redisPort := config.RedisPort
+
+			if redisHost == "" {
+				redisHost = "localhost"
+			}
+			if redisPort == "" {
+				redisPort = "6379"
+			}
+
+			redisAddr := fmt.Sprintf("%s:%s", redisHost, redisPort)
+			redisClient = redis.NewClient(&redis.Options{
+				Addr:     redisAddr,
+				Password: config.RedisPassword,
+				DB:       0,
+			})
+			response.RedisHost = redisHost
+			log.Printf("Connecting to Redis at %s", redisAddr)
+		}
+
 		// Test the connection
 		_, err := redisClient.Ping(ctx).Result()
 		if err != nil {
@@ -385,9 +1113,9 @@ func runBenchmark(config Configuration) Response {
 			response.Error = fmt.Sprintf("Failed to connect to Redis: %v", err)
 			return response
 		}
-		
-		response.RedisHost = redisHost
-		log.Printf("Connected to Redis at %s", redisAddr)
+
+		backend = &goRedisBackend{client: redisClient}
+		log.Printf("Connected to Redis (mode: %s)", config.RedisMode)
 	}
 	
 	// Run the benchmark operations
@@ -398,18 +1126,51 @@ func runBenchmark(config Configuration) Response {
 	var wg sync.WaitGroup
 	
 	// Create worker functions
+	pipelined := !config.UseBatching && config.OperationMode != "individual"
+
 	workerFunc := func(start, end int) {
 		defer wg.Done()
-		
+
+		if pipelined {
+			for batchStart := start; batchStart < end; batchStart += config.PipelineSize {
+				batchEnd := batchStart + config.PipelineSize
+				if batchEnd > end {
+					batchEnd = end
+				}
+
+				keys := make([]string, 0, batchEnd-batchStart)
+				values := make([]string, 0, batchEnd-batchStart)
+				for i := batchStart; i < batchEnd; i++ {
+					keys = append(keys, fmt.Sprintf("%s_%d", config.KeyPrefix, i))
+					values = append(values, fmt.Sprintf("value_%d", i))
+				}
+
+				results, err := pipelinedRedisOperation(ctx, redisClient, config.OperationMode, config.OperationType, keys, values)
+				if err != nil {
+					for _, key := range keys {
+						resultsChan <- OperationResult{Key: key, Status: "error", Error: err.Error()}
+					}
+					continue
+				}
+				for _, result := range results {
+					if result.Status == "success" {
+						successCount++
+					}
+					resultsChan <- result
+				}
+			}
+			return
+		}
+
 		for i := start; i < end; i++ {
 			key := fmt.Sprintf("%s_%d", config.KeyPrefix, i)
-			value := fmt.Sprintf("value_%d", i)
-			
+			value := genValue(i, config.ValueSize)
+
 			var result OperationResult
 			result.Key = key
-			
+
 			opStart := time.Now()
-			
+
 			if config.UseBatching {
 				// Use batching agent
 				val, err := batchedRedisOperation(batchingURL, config.OperationType, key, value)
@@ -421,9 +1182,25 @@ func runBenchmark(config Configuration) Response {
 					result.Value = val
 					successCount++
 				}
+			} else if dataTypeOps[config.OperationType] {
+				// Direct Redis access, list/hash/stream/geo/pub-sub workloads
+				dtResult, err := dataTypeRedisOperation(ctx, redisClient, config, key, value)
+				dtResult.Key = key
+				dtResult.Shard = shardFor(redisClient, config.RedisMode, key)
+				if err != nil && err != redis.Nil {
+					dtResult.Status = "error"
+					dtResult.Error = err.Error()
+				} else {
+					dtResult.Status = "success"
+					successCount++
+				}
+				result = dtResult
 			} else {
-				// Direct Redis access
-				val, err := directRedisOperation(ctx, redisClient, config.OperationType, key, value)
+				// Direct Redis access, through whichever RedisBackend was
+				// selected by config.ClientLibrary
+				result.Shard = shardFor(redisClient, config.RedisMode, key)
+				val, cacheHit, err := backend.Do(ctx, config.OperationType, key, value)
+				result.CacheHit = cacheHit
 				if err != nil && err != redis.Nil {
 					result.Status = "error"
 					result.Error = err.Error()
@@ -437,7 +1214,7 @@ func runBenchmark(config Configuration) Response {
 					successCount++
 				}
 			}
-			
+
 			result.DurationMs = float64(time.Since(opStart)) / float64(time.Millisecond)
 			resultsChan <- result
 		}
@@ -466,17 +1243,60 @@ func runBenchmark(config Configuration) Response {
 		close(resultsChan)
 	}()
 	
-	// Collect results
+	// Collect results. Every result's duration is recorded into an overall
+	// and a per-shard histogram regardless of ReportMode; whether the raw
+	// OperationResult itself is kept in response.Results depends on it.
+	overall := newLatencyHistogram()
+	perShard := make(map[string]*latencyHistogram)
 	for result := range resultsChan {
-		response.Results = append(response.Results, result)
+		overall.record(result.DurationMs)
+
+		shard := result.Shard
+		if shard == "" {
+			shard = "single"
+		}
+		shardHist, ok := perShard[shard]
+		if !ok {
+			shardHist = newLatencyHistogram()
+			perShard[shard] = shardHist
+		}
+		shardHist.record(result.DurationMs)
+
+		switch config.ReportMode {
+		case "summary":
+			// Stats only; raw results are dropped.
+		case "histogram":
+			if len(response.Results) < resultsSampleCap {
+				response.Results = append(response.Results, result)
+			}
+		default:
+			response.Results = append(response.Results, result)
+		}
 	}
-	
+
 	response.SuccessCount = successCount
 	response.ExecutionTimeMs = float64(time.Since(startTime)) / float64(time.Millisecond)
+
+	if config.ReportMode == "summary" || config.ReportMode == "histogram" {
+		stats := &Stats{LatencyStats: overall.latencyStats()}
+		if response.ExecutionTimeMs > 0 {
+			stats.ThroughputOpsSec = float64(overall.count) / (response.ExecutionTimeMs / 1000)
+		}
+		if len(perShard) > 1 {
+			stats.PerShard = make(map[string]LatencyStats, len(perShard))
+			for shard, hist := range perShard {
+				stats.PerShard[shard] = hist.latencyStats()
+			}
+		}
+		if config.ReportMode == "histogram" {
+			stats.Buckets = overall.histogramBuckets()
+		}
+		response.Stats = stats
+	}
 	
-	// Close Redis client
-	if redisClient != nil {
-		redisClient.Close()
+	// Close the Redis backend/client
+	if backend != nil {
+		backend.Close()
 	}
 	
 	return response
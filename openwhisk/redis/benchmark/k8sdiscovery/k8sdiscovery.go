@@ -0,0 +1,121 @@
+// Package k8sdiscovery resolves node and pod addresses from inside a
+// cluster using client-go, replacing hand-rolled bearer-token HTTP calls
+// against the Kubernetes API.
+package k8sdiscovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// AgentEndpoint is a resolved batching-agent address: a pod IP and the
+// container port it listens on, as declared on the DaemonSet's pod spec.
+type AgentEndpoint struct {
+	PodIP string
+	Port  int32
+}
+
+// Discoverer resolves this pod's node and sibling pods on that node via the
+// in-cluster Kubernetes API, honoring the mounted CA cert (no
+// InsecureSkipVerify).
+type Discoverer struct {
+	clientset *kubernetes.Clientset
+	namespace string
+
+	selfOnce sync.Once
+	selfErr  error
+	hostIP   string
+	nodeName string
+}
+
+// NewInClusterDiscoverer builds a Discoverer using rest.InClusterConfig() and
+// the namespace file the service account token is mounted alongside.
+func NewInClusterDiscoverer() (*Discoverer, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	namespace, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read namespace: %w", err)
+	}
+	return &Discoverer{clientset: clientset, namespace: string(namespace)}, nil
+}
+
+// self resolves and caches this pod's HostIP and NodeName by looking itself
+// up by hostname (the pod name inside Kubernetes).
+func (d *Discoverer) self(ctx context.Context) (string, string, error) {
+	d.selfOnce.Do(func() {
+		hostname, err := os.Hostname()
+		if err != nil {
+			d.selfErr = fmt.Errorf("failed to get hostname: %w", err)
+			return
+		}
+		pod, err := d.clientset.CoreV1().Pods(d.namespace).Get(ctx, hostname, metav1.GetOptions{})
+		if err != nil {
+			d.selfErr = fmt.Errorf("failed to get self pod %q: %w", hostname, err)
+			return
+		}
+		d.hostIP = pod.Status.HostIP
+		d.nodeName = pod.Spec.NodeName
+	})
+	return d.hostIP, d.nodeName, d.selfErr
+}
+
+// NodeIP returns the IP of the Kubernetes node this pod is scheduled on.
+func (d *Discoverer) NodeIP(ctx context.Context) (string, error) {
+	hostIP, _, err := d.self(ctx)
+	return hostIP, err
+}
+
+// FindAgentOnNode lists pods matching labelSelector and returns the one
+// scheduled on this pod's node, resolving its IP and first declared
+// container port. It's meant for a DaemonSet-backed batching agent: exactly
+// one matching pod should be running per node.
+func (d *Discoverer) FindAgentOnNode(ctx context.Context, labelSelector string) (*AgentEndpoint, error) {
+	_, nodeName, err := d.self(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := d.clientset.CoreV1().Pods(d.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: "spec.nodeName=" + nodeName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods for selector %q: %w", labelSelector, err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		port, ok := firstContainerPort(&pod)
+		if !ok {
+			continue
+		}
+		return &AgentEndpoint{PodIP: pod.Status.PodIP, Port: port}, nil
+	}
+
+	return nil, fmt.Errorf("no running pod matching selector %q found on node %q", labelSelector, nodeName)
+}
+
+func firstContainerPort(pod *corev1.Pod) (int32, bool) {
+	for _, c := range pod.Spec.Containers {
+		if len(c.Ports) > 0 {
+			return c.Ports[0].ContainerPort, true
+		}
+	}
+	return 0, false
+}
@@ -14,9 +14,14 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/gorilla/mux"
 	"github.com/serverless-benchmarks/openwhisk/batching-agent/pkg/batching"
 )
@@ -34,6 +39,7 @@ type Configuration struct {
 	BatchingEnabled   bool
 	BatchWindow       time.Duration
 	MaxBatchSize      int
+	MaxBufferBytes    int64
 	DebugMode         bool
 	AwsRegion         string
 	DefaultBucketName string
@@ -51,52 +57,22 @@ type BatchingAgent struct {
 
 // NewBatchingAgent creates a new batching agent
 func NewBatchingAgent(config Configuration) (*BatchingAgent, error) {
-	// Debug: Print environment variables
-	log.Printf("Checking AWS environment variables...")
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	if accessKey != "" {
-		log.Printf("AWS_ACCESS_KEY_ID is set (length: %d, prefix: %s)", len(accessKey), accessKey[:4])
-	} else {
-		log.Printf("AWS_ACCESS_KEY_ID is not set")
-	}
-	if secretKey != "" {
-		log.Printf("AWS_SECRET_ACCESS_KEY is set (length: %d, prefix: %s)", len(secretKey), secretKey[:4])
-	} else {
-		log.Printf("AWS_SECRET_ACCESS_KEY is not set")
-	}
-
-	// Configure AWS SDK with explicit credentials
-	creds := credentials.NewStaticCredentialsProvider(
-		accessKey,
-		secretKey,
-		"",
-	)
-
-	// Test credentials before creating config
-	credsValue, err := creds.Retrieve(context.Background())
-	if err != nil {
-		log.Printf("Failed to retrieve credentials: %v", err)
-		return nil, fmt.Errorf("failed to retrieve credentials: %w", err)
-	}
-	log.Printf("Successfully retrieved credentials - Access Key: %s, Secret Key: %s", 
-		credsValue.AccessKeyID[:4], 
-		credsValue.SecretAccessKey[:4])
-
-	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), 
+	// Load the standard SDK chain (env, shared config/SSO, EC2 instance
+	// role, IRSA web identity) rather than pinning a single provider, so
+	// the agent keeps working under IAM roles and IRSA instead of only
+	// static keys.
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(),
 		awsconfig.WithRegion(config.AwsRegion),
-		awsconfig.WithCredentialsProvider(creds),
 	)
 	if err != nil {
-		log.Printf("Failed to load AWS configuration: %v", err)
 		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
 	}
+	cfg.Credentials = resolveCredentialsProvider(cfg)
 
-	// Debug: Print AWS configuration details
-	log.Printf("AWS Configuration loaded - Region: %s, Credentials: %v", config.AwsRegion, cfg.Credentials != nil)
-	if cfg.Credentials != nil {
-		log.Printf("Credentials provider type: %T", cfg.Credentials)
-	}
+	// STS-issued credentials (EC2 instance role, IRSA) expire; log when
+	// they're close to doing so. aws.CredentialsCache refreshes them
+	// transparently on the next Retrieve, this just surfaces it in logs.
+	go watchCredentialExpiration(context.Background(), cfg.Credentials)
 
 	s3Client := s3.NewFromConfig(cfg)
 
@@ -121,6 +97,7 @@ func NewBatchingAgent(config Configuration) (*BatchingAgent, error) {
 		config.BatchingEnabled,
 		config.BatchWindow,
 		config.MaxBatchSize,
+		config.MaxBufferBytes,
 	)
 
 	// Set up routes
@@ -129,6 +106,74 @@ func NewBatchingAgent(config Configuration) (*BatchingAgent, error) {
 	return agent, nil
 }
 
+// resolveCredentialsProvider picks the credentials source for the agent. It
+// prefers the standard SDK chain already resolved onto cfg.Credentials (env,
+// shared config/SSO, EC2 instance role via IMDS, IRSA web identity via STS),
+// falling back to AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY only when both are
+// set explicitly. Either way the result is wrapped in aws.CredentialsCache so
+// expiring STS/IMDS credentials are transparently refreshed.
+func resolveCredentialsProvider(cfg aws.Config) aws.CredentialsProvider {
+	if accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); accessKey != "" && secretKey != "" {
+		log.Printf("AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY set, using static credentials instead of the default chain")
+		return aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""))
+	}
+
+	chain := &credentialsChain{providers: []aws.CredentialsProvider{
+		cfg.Credentials,
+		ec2rolecreds.New(func(o *ec2rolecreds.Options) { o.Client = imds.NewFromConfig(cfg) }),
+		stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(cfg), os.Getenv("AWS_ROLE_ARN"), stscreds.IdentityTokenFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))),
+	}}
+	return aws.NewCredentialsCache(chain)
+}
+
+// credentialsChain tries each provider in turn, returning the first one that
+// succeeds. It lets us fall back to explicit ec2rolecreds/stscreds providers
+// if whatever config.LoadDefaultConfig resolved onto cfg.Credentials fails.
+type credentialsChain struct {
+	providers []aws.CredentialsProvider
+}
+
+func (c *credentialsChain) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	var lastErr error
+	for _, provider := range c.providers {
+		creds, err := provider.Retrieve(ctx)
+		if err == nil {
+			return creds, nil
+		}
+		lastErr = err
+	}
+	return aws.Credentials{}, fmt.Errorf("no credentials provider succeeded: %w", lastErr)
+}
+
+// watchCredentialExpiration periodically retrieves creds and logs when they
+// are close to expiring. aws.CredentialsCache performs the actual refresh on
+// the next Retrieve call; this just gives early warning and forces that
+// pre-refresh ahead of an in-flight request hitting expired credentials.
+func watchCredentialExpiration(ctx context.Context, creds aws.CredentialsProvider) {
+	const checkInterval = 5 * time.Minute
+	const refreshBefore = 10 * time.Minute
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		value, err := creds.Retrieve(ctx)
+		if err != nil {
+			log.Printf("Failed to refresh AWS credentials: %v", err)
+		} else if value.CanExpire {
+			if remaining := time.Until(value.Expires); remaining < refreshBefore {
+				log.Printf("AWS credentials expire in %s, pre-refreshing", remaining.Round(time.Second))
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // setupRoutes configures the HTTP routes
 func (a *BatchingAgent) setupRoutes() {
 	// Health check
@@ -338,6 +383,7 @@ func main() {
 	batchingEnabled := flag.Bool("batching", true, "Enable request batching")
 	batchWindow := flag.Duration("batch-window", 100*time.Millisecond, "Batch window duration")
 	maxBatchSize := flag.Int("max-batch-size", 10, "Maximum batch size")
+	maxBufferBytes := flag.Int64("max-buffer-bytes", 64*1024*1024, "Cap on how much of a coalesced GetObject body is buffered in memory before spilling to a temp file")
 	debugMode := flag.Bool("debug", false, "Enable debug mode")
 	awsRegion := flag.String("aws-region", "us-east-1", "AWS region")
 	defaultBucketName := flag.String("default-bucket", "", "Default S3 bucket name")
@@ -351,6 +397,7 @@ func main() {
 		BatchingEnabled:   *batchingEnabled,
 		BatchWindow:       *batchWindow,
 		MaxBatchSize:      *maxBatchSize,
+		MaxBufferBytes:    *maxBufferBytes,
 		DebugMode:         *debugMode,
 		AwsRegion:         *awsRegion,
 		DefaultBucketName: *defaultBucketName,